@@ -0,0 +1,293 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultDir is the state directory used by the CLI, relative to the
+// directory of the definition file.
+const DefaultDir = ".opsrunner"
+
+const stateFileName = "state.json"
+
+// Store keeps string values, such as hashes of the inputs of a successful
+// run, in a JSON file inside the state directory.
+type Store struct {
+	mu  sync.Mutex
+	dir string
+}
+
+// NewStore creates a store backed by the given directory. The directory is
+// created on the first write.
+func NewStore(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// Dir returns the state directory of the store.
+func (s *Store) Dir() string {
+	return s.dir
+}
+
+// Get returns the value stored under the key, if any.
+func (s *Store) Get(key string) (string, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, err := s.read()
+	if err != nil {
+		return "", false, err
+	}
+	value, ok := state[key]
+	return value, ok, nil
+}
+
+// Put stores the value under the key.
+func (s *Store) Put(key string, value string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, err := s.read()
+	if err != nil {
+		return err
+	}
+	state[key] = value
+	return s.write(state)
+}
+
+// Delete removes the keys from the store.
+func (s *Store) Delete(keys ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, err := s.read()
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		delete(state, key)
+	}
+	return s.write(state)
+}
+
+// Clear removes the state directory with everything in it.
+func (s *Store) Clear() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.RemoveAll(s.dir); err != nil {
+		return fmt.Errorf("failed to clear cache: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) read() (map[string]string, error) {
+	state := make(map[string]string)
+	content, err := os.ReadFile(filepath.Join(s.dir, stateFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read cache state: %w", err)
+	}
+	if err := json.Unmarshal(content, &state); err != nil {
+		return nil, fmt.Errorf("failed to decode cache state: %w", err)
+	}
+	return state, nil
+}
+
+func (s *Store) write(state map[string]string) error {
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	// Keep the state out of version control.
+	ignoreFile := filepath.Join(s.dir, ".gitignore")
+	if _, err := os.Stat(ignoreFile); errors.Is(err, os.ErrNotExist) {
+		_ = os.WriteFile(ignoreFile, []byte("*\n"), 0o644)
+	}
+	content, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(s.dir, stateFileName)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(content, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write cache state: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// HashFiles returns a hash of the given values together with the paths
+// and contents of the files matching the glob patterns. Patterns that
+// match no file are part of the hash, so creating a matching file later
+// changes it. A ** segment in a pattern matches any number of directories.
+func HashFiles(values []string, patterns []string) (string, error) {
+	hash := sha256.New()
+	for _, value := range values {
+		_, _ = fmt.Fprintf(hash, "value:%s\n", value)
+	}
+	var files []string
+	for _, pattern := range patterns {
+		matches, err := glob(pattern)
+		if err != nil {
+			return "", fmt.Errorf("invalid pattern '%s': %w", pattern, err)
+		}
+		if len(matches) == 0 {
+			_, _ = fmt.Fprintf(hash, "missing:%s\n", pattern)
+		}
+		files = append(files, matches...)
+	}
+	sort.Strings(files)
+	for idx, path := range files {
+		if idx > 0 && files[idx-1] == path {
+			continue
+		}
+		if err := hashFile(hash, path); err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// skippedDirs are never searched by ** patterns, as their content changes
+// with every run.
+var skippedDirs = []string{".git", DefaultDir}
+
+// glob returns the paths matching the pattern like filepath.Glob, with
+// support for ** segments matching any number of directories. Those only
+// match files, not the directories themselves.
+func glob(pattern string) ([]string, error) {
+	if !strings.Contains(pattern, "**") {
+		return filepath.Glob(pattern)
+	}
+	segments := strings.Split(filepath.ToSlash(pattern), "/")
+	literal := 0
+	for literal < len(segments) && !strings.ContainsAny(segments[literal], "*?[") {
+		literal++
+	}
+	for _, segment := range segments[literal:] {
+		if segment != "**" && strings.Contains(segment, "**") {
+			return nil, errors.New("** must be a whole path segment")
+		}
+		if _, err := path.Match(segment, ""); err != nil {
+			return nil, err
+		}
+	}
+	root := filepath.FromSlash(strings.Join(segments[:literal], "/"))
+	if root == "" {
+		root = "."
+		if strings.HasPrefix(pattern, "/") {
+			root = "/"
+		}
+	}
+	var matches []string
+	err := filepath.WalkDir(root, func(child string, entry os.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if entry.IsDir() {
+			if child != root && slices.Contains(skippedDirs, entry.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(root, child)
+		if err != nil {
+			return err
+		}
+		if matchSegments(segments[literal:], strings.Split(filepath.ToSlash(rel), "/")) {
+			matches = append(matches, child)
+		}
+		return nil
+	})
+	return matches, err
+}
+
+// matchSegments reports whether the path segments match the pattern
+// segments, where ** matches any number of path segments.
+func matchSegments(pattern []string, segments []string) bool {
+	if len(pattern) == 0 {
+		return len(segments) == 0
+	}
+	if pattern[0] == "**" {
+		for skip := 0; skip <= len(segments); skip++ {
+			if matchSegments(pattern[1:], segments[skip:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(segments) == 0 {
+		return false
+	}
+	matched, _ := path.Match(pattern[0], segments[0])
+	return matched && matchSegments(pattern[1:], segments[1:])
+}
+
+func hashFile(w io.Writer, path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to hash %s: %w", path, err)
+	}
+	if info.IsDir() {
+		return filepath.WalkDir(path, func(child string, entry os.DirEntry, err error) error {
+			if err != nil || entry.IsDir() || child == path {
+				return err
+			}
+			return hashFile(w, child)
+		})
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to hash %s: %w", path, err)
+	}
+	defer file.Close()
+	_, _ = fmt.Fprintf(w, "file:%s:%d\n", filepath.ToSlash(path), info.Size())
+	if _, err := io.Copy(w, file); err != nil {
+		return fmt.Errorf("failed to hash %s: %w", path, err)
+	}
+	return nil
+}
+
+// SplitPatterns splits a comma-separated list of glob patterns.
+func SplitPatterns(list string) []string {
+	var patterns []string
+	for _, pattern := range strings.Split(list, ",") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			patterns = append(patterns, pattern)
+		}
+	}
+	return patterns
+}
+
+type storeKey string
+
+const storeKeyName storeKey = "cache"
+
+// AddToContext adds a store to the context for later retrieval
+func AddToContext(ctx context.Context, store *Store) context.Context {
+	return context.WithValue(ctx, storeKeyName, store)
+}
+
+// FromContext retrieves the store from the context, or returns nil if
+// caching is disabled
+func FromContext(ctx context.Context) *Store {
+	if store, ok := ctx.Value(storeKeyName).(*Store); ok {
+		return store
+	}
+	return nil
+}
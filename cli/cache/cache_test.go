@@ -0,0 +1,123 @@
+package cache
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), DefaultDir)
+	store := NewStore(dir)
+
+	_, ok, err := store.Get("install")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	require.NoError(t, store.Put("install", "abc"))
+	require.NoError(t, store.Put("task:test", "def"))
+	value, ok, err := NewStore(dir).Get("install")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "abc", value)
+	assert.FileExists(t, filepath.Join(dir, ".gitignore"))
+
+	require.NoError(t, store.Delete("install"))
+	_, ok, err = store.Get("install")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	require.NoError(t, store.Clear())
+	assert.NoDirExists(t, dir)
+}
+
+func TestStoreCorruptState(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, stateFileName), []byte("{"), 0o644))
+	_, _, err := NewStore(dir).Get("install")
+	assert.ErrorContains(t, err, "failed to decode cache state")
+}
+
+func TestHashFiles(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, content string) {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+	write("go.mod", "module demo")
+	write("go.sum", "")
+	patterns := []string{filepath.Join(dir, "go.*"), filepath.Join(dir, "go.mod")}
+
+	first, err := HashFiles([]string{"go mod download"}, patterns)
+	require.NoError(t, err)
+	again, err := HashFiles([]string{"go mod download"}, patterns)
+	require.NoError(t, err)
+	assert.Equal(t, first, again)
+
+	otherCommand, err := HashFiles([]string{"go mod tidy"}, patterns)
+	require.NoError(t, err)
+	assert.NotEqual(t, first, otherCommand)
+
+	write("go.sum", "golang.org/x/term v0.1.0 h1:...")
+	changed, err := HashFiles([]string{"go mod download"}, patterns)
+	require.NoError(t, err)
+	assert.NotEqual(t, first, changed)
+
+	// Directories are hashed recursively.
+	write("src/main.go", "package main")
+	before, err := HashFiles(nil, []string{filepath.Join(dir, "src")})
+	require.NoError(t, err)
+	write("src/pkg/util.go", "package pkg")
+	after, err := HashFiles(nil, []string{filepath.Join(dir, "src")})
+	require.NoError(t, err)
+	assert.NotEqual(t, before, after)
+}
+
+func TestHashFilesRecursivePattern(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, content string) {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+	write("api/v1/user.proto", "message User {}")
+	write("api/v1/types/id.proto", "message ID {}")
+	write("api/README.md", "")
+
+	matches, err := glob(filepath.Join(dir, "api", "**", "*.proto"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(dir, "api", "v1", "types", "id.proto"),
+		filepath.Join(dir, "api", "v1", "user.proto"),
+	}, matches)
+
+	patterns := []string{filepath.Join(dir, "**", "*.proto")}
+	before, err := HashFiles(nil, patterns)
+	require.NoError(t, err)
+	write("api/README.md", "changed")
+	write(DefaultDir+"/cached.proto", "")
+	unchanged, err := HashFiles(nil, patterns)
+	require.NoError(t, err)
+	assert.Equal(t, before, unchanged)
+	write("api/v1/types/id.proto", "message ID { string value = 1; }")
+	changed, err := HashFiles(nil, patterns)
+	require.NoError(t, err)
+	assert.NotEqual(t, before, changed)
+
+	_, err = HashFiles(nil, []string{"api/v**/*.proto"})
+	assert.EqualError(t, err, "invalid pattern 'api/v**/*.proto': ** must be a whole path segment")
+}
+
+func TestSplitPatterns(t *testing.T) {
+	assert.Equal(t, []string{"go.mod", "go.sum"}, SplitPatterns(" go.mod, go.sum ,"))
+	assert.Empty(t, SplitPatterns(""))
+}
+
+func TestContext(t *testing.T) {
+	assert.Nil(t, FromContext(context.Background()))
+	store := NewStore(t.TempDir())
+	assert.Same(t, store, FromContext(AddToContext(context.Background(), store)))
+}
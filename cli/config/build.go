@@ -5,35 +5,138 @@ import (
 	"fmt"
 	"time"
 
+	"gtithub.com/jgfranco17/opsrunner/cli/cache"
 	"gtithub.com/jgfranco17/opsrunner/cli/logging"
 )
 
+const (
+	installNode = "install"
+	buildNode   = "build"
+)
+
+// installCacheKey is the cache entry recording the dependencies of the
+// last successful install.
+const installCacheKey = "install"
+
 type BuildOptions struct {
 	NoInstall bool
+	// ForceInstall runs the install operation even when the dependencies
+	// did not change since the last successful install.
+	ForceInstall bool
+	// Force runs tasks even when their inputs and outputs did not change
+	// since their last successful run.
+	Force bool
 }
 
 func Build(ctx context.Context, shellExecutor ShellExecutor, config *ProjectDefinition, opts *BuildOptions) error {
 	logger := logging.FromContext(ctx)
 	startTime := time.Now()
 
+	graph, err := NewProjectGraph(shellExecutor, config, opts)
+	if err != nil {
+		return err
+	}
+	err = config.Hooks.around(ctx, shellExecutor, func(ctx context.Context) error {
+		return graph.Execute(ctx, buildNode)
+	})
+	if err != nil {
+		return err
+	}
+	duration := time.Since(startTime)
+	logger.Infof("Build completed successfully in %dms", duration.Milliseconds())
+	return nil
+}
+
+// NewProjectGraph creates the dependency graph for a project. The codebase
+// install and build operations are registered as the "install" and "build"
+// nodes, alongside one node per task. The project shell is given to the
+// operations that define none, as for definitions read with Load.
+func NewProjectGraph(shellExecutor ShellExecutor, config *ProjectDefinition, opts *BuildOptions) (*Graph, error) {
 	if opts == nil {
 		opts = &BuildOptions{}
 	}
-	if !opts.NoInstall {
-		logger.Debug("Installing codebase dependencies")
-		if err := config.Codebase.Install.Run(ctx, shellExecutor); err != nil {
-			return fmt.Errorf("failed to install codebase dependencies: %w", err)
+	config.applyShell()
+	graph := NewGraph()
+	install := Node{
+		Name: installNode,
+		Run: func(ctx context.Context) error {
+			logger := logging.FromContext(ctx)
+			if opts.NoInstall || config.Profiles[config.ActiveProfile].NoInstall {
+				logger.Info("Skipping codebase dependency installation")
+				return nil
+			}
+			// Skipped installs must not be recorded as up to date.
+			if run, err := config.Codebase.Install.enabled(); err != nil {
+				return fmt.Errorf("failed to install codebase dependencies: %w", err)
+			} else if !run {
+				logger.Infof("Skipping installation, condition not met: %s", config.Codebase.Install.If)
+				return nil
+			}
+			store := cache.FromContext(ctx)
+			key, err := config.Codebase.installKey()
+			if err != nil {
+				logger.Warnf("Not caching the install: %v", err)
+			}
+			if store != nil && key != "" && !opts.ForceInstall {
+				if last, _, err := store.Get(installCacheKey); err != nil {
+					logger.Warnf("Ignoring the install cache: %v", err)
+				} else if last == key {
+					logger.Info("Dependencies unchanged since the last install, skipping installation")
+					return nil
+				}
+			}
+			logger.Debug("Installing codebase dependencies")
+			if err := config.Codebase.Install.Run(ctx, shellExecutor); err != nil {
+				return fmt.Errorf("failed to install codebase dependencies: %w", err)
+			}
+			if store != nil && key != "" {
+				if err := store.Put(installCacheKey, key); err != nil {
+					logger.Warnf("Failed to record the install: %v", err)
+				}
+			}
+			return nil
+		},
+	}
+	build := Node{
+		Name:      buildNode,
+		DependsOn: []string{installNode},
+		Run: func(ctx context.Context) error {
+			logger := logging.FromContext(ctx)
+			if len(config.Codebase.Build.Steps) == 0 {
+				logger.Warn("No build steps defined in the configuration.")
+			}
+			if err := config.Codebase.Build.Run(ctx, shellExecutor); err != nil {
+				return fmt.Errorf("failed to run build steps: %w", err)
+			}
+			return nil
+		},
+	}
+	for _, node := range []Node{install, build} {
+		if err := graph.Add(node); err != nil {
+			return nil, err
+		}
+	}
+	for _, name := range config.TaskNames() {
+		if name == installNode || name == buildNode {
+			return nil, fmt.Errorf("task name '%s' is reserved for the codebase %s operation", name, name)
+		}
+		if err := graph.Add(taskNode(shellExecutor, name, config.Tasks[name], opts)); err != nil {
+			return nil, err
 		}
-	} else {
-		logger.Info("Skipping codebase dependency installation")
 	}
-	if len(config.Codebase.Build.Steps) == 0 {
-		logger.Warn("No build steps defined in the configuration.")
+	return graph, nil
+}
+
+// installKey hashes the dependency files of the codebase together with
+// the install operation. It is empty when no dependency files are declared.
+func (c *Codebase) installKey() (string, error) {
+	patterns := cache.SplitPatterns(c.Dependencies)
+	if len(patterns) == 0 {
+		return "", nil
 	}
-	if err := config.Codebase.Build.Run(ctx, shellExecutor); err != nil {
-		return fmt.Errorf("failed to run build steps: %w", err)
+	definition, err := c.Install.definition()
+	if err != nil {
+		return "", err
 	}
-	duration := time.Since(startTime)
-	logger.Infof("Build completed successfully in %dms", duration.Milliseconds())
-	return nil
+	return cache.HashFiles([]string{definition}, patterns)
 }
@@ -0,0 +1,90 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gtithub.com/jgfranco17/opsrunner/cli/cache"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildSkipsUnchangedInstall(t *testing.T) {
+	dir := t.TempDir()
+	lockfile := filepath.Join(dir, "go.sum")
+	require.NoError(t, os.WriteFile(lockfile, []byte("v1"), 0o644))
+	cfg := &ProjectDefinition{
+		Codebase: Codebase{
+			Dependencies: lockfile,
+			Install:      Operation{Steps: []Step{{Run: "go mod download"}}},
+			Build:        Operation{Steps: []Step{{Run: "go build"}}},
+		},
+	}
+	ctx := cache.AddToContext(context.Background(), cache.NewStore(filepath.Join(dir, cache.DefaultDir)))
+
+	exec := &fakeExecutor{}
+	require.NoError(t, Build(ctx, exec, cfg, nil))
+	require.NoError(t, Build(ctx, exec, cfg, nil))
+	assert.Equal(t, []string{"go mod download", "go build", "go build"}, exec.commands)
+
+	exec = &fakeExecutor{}
+	require.NoError(t, Build(ctx, exec, cfg, &BuildOptions{ForceInstall: true}))
+	assert.Equal(t, []string{"go mod download", "go build"}, exec.commands)
+
+	exec = &fakeExecutor{}
+	require.NoError(t, os.WriteFile(lockfile, []byte("v2"), 0o644))
+	require.NoError(t, Build(ctx, exec, cfg, nil))
+	assert.Equal(t, []string{"go mod download", "go build"}, exec.commands)
+}
+
+func TestBuildDoesNotRecordFailedInstall(t *testing.T) {
+	dir := t.TempDir()
+	lockfile := filepath.Join(dir, "go.sum")
+	require.NoError(t, os.WriteFile(lockfile, []byte("v1"), 0o644))
+	cfg := &ProjectDefinition{
+		Codebase: Codebase{
+			Dependencies: lockfile,
+			Install:      Operation{FailFast: true, Steps: []Step{{Run: "go mod download"}}},
+			Build:        Operation{Steps: []Step{{Run: "go build"}}},
+		},
+	}
+	store := cache.NewStore(filepath.Join(dir, cache.DefaultDir))
+	ctx := cache.AddToContext(context.Background(), store)
+
+	exec := &fakeExecutor{exitCodes: map[string]int{"go mod download": 1}}
+	assert.Error(t, Build(ctx, exec, cfg, nil))
+	_, ok, err := store.Get(installCacheKey)
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestBuildDoesNotRecordSkippedInstall(t *testing.T) {
+	dir := t.TempDir()
+	lockfile := filepath.Join(dir, "go.sum")
+	require.NoError(t, os.WriteFile(lockfile, []byte("v1"), 0o644))
+	cfg := &ProjectDefinition{
+		Codebase: Codebase{
+			Dependencies: lockfile,
+			Install:      Operation{If: `env.DO_INSTALL == "yes"`, Steps: []Step{{Run: "go mod download"}}},
+			Build:        Operation{Steps: []Step{{Run: "go build"}}},
+		},
+	}
+	store := cache.NewStore(filepath.Join(dir, cache.DefaultDir))
+	ctx := cache.AddToContext(context.Background(), store)
+
+	t.Setenv("DO_INSTALL", "no")
+	exec := &fakeExecutor{}
+	require.NoError(t, Build(ctx, exec, cfg, nil))
+	assert.Equal(t, []string{"go build"}, exec.commands)
+	_, ok, err := store.Get(installCacheKey)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	t.Setenv("DO_INSTALL", "yes")
+	exec = &fakeExecutor{}
+	require.NoError(t, Build(ctx, exec, cfg, nil))
+	assert.Equal(t, []string{"go mod download", "go build"}, exec.commands)
+}
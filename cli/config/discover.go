@@ -0,0 +1,45 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefinitionFileNames are the names Discover looks for, in order of
+// preference.
+var DefinitionFileNames = []string{".opsrunner.yaml", ".opsrunner.yml", "opsrunner.yaml"}
+
+// Discover searches the start directory and its parents for a definition
+// file, stopping at the root of the enclosing git repository, or at the
+// filesystem root outside of one. It returns the absolute path of the
+// first file found.
+func Discover(start string) (string, error) {
+	start, err := filepath.Abs(start)
+	if err != nil {
+		return "", err
+	}
+	dir := start
+	for {
+		for _, name := range DefinitionFileNames {
+			path := filepath.Join(dir, name)
+			if info, err := os.Stat(path); err == nil && !info.IsDir() {
+				return path, nil
+			}
+		}
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	searched := start
+	if dir != start {
+		searched = fmt.Sprintf("%s or its parents up to %s", start, dir)
+	}
+	return "", fmt.Errorf("no definition file found in %s (looked for %s)", searched, strings.Join(DefinitionFileNames, ", "))
+}
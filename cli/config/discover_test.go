@@ -0,0 +1,52 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiscoverSearchesParents(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"repo/.git/HEAD":              "",
+		"repo/.opsrunner.yml":         "name: repo",
+		"repo/service/opsrunner.yaml": "name: service",
+	})
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "repo", "service", "cmd", "app"), 0o755))
+
+	path, err := Discover(filepath.Join(dir, "repo", "service", "cmd", "app"))
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "repo", "service", "opsrunner.yaml"), path)
+
+	path, err = Discover(filepath.Join(dir, "repo", ".git"))
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "repo", ".opsrunner.yml"), path)
+}
+
+func TestDiscoverPrefersHiddenYaml(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		".git/HEAD":       "",
+		".opsrunner.yaml": "name: a",
+		".opsrunner.yml":  "name: b",
+		"opsrunner.yaml":  "name: c",
+	})
+	path, err := Discover(dir)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, ".opsrunner.yaml"), path)
+}
+
+func TestDiscoverStopsAtGitRoot(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		".opsrunner.yaml": "name: outside",
+		"repo/.git/HEAD":  "",
+	})
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "repo", "src"), 0o755))
+
+	_, err := Discover(filepath.Join(dir, "repo", "src"))
+	expected := "no definition file found in " + filepath.Join(dir, "repo", "src") + " or its parents up to " +
+		filepath.Join(dir, "repo") + " (looked for .opsrunner.yaml, .opsrunner.yml, opsrunner.yaml)"
+	assert.EqualError(t, err, expected)
+}
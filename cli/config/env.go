@@ -0,0 +1,88 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// EnvMode controls which variables of the calling environment are passed
+// on to the steps of an operation.
+type EnvMode string
+
+const (
+	// EnvModeInherit passes on the full calling environment.
+	EnvModeInherit EnvMode = "inherit"
+	// EnvModeClean passes on only PATH and HOME.
+	EnvModeClean EnvMode = "clean"
+	// EnvModeAllowlist passes on PATH, HOME and the allowlisted variables.
+	EnvModeAllowlist EnvMode = "allowlist"
+)
+
+// baselineEnv is kept in every mode so that steps can locate tools.
+var baselineEnv = []string{"PATH", "HOME"}
+
+func (m EnvMode) enumValues() []string {
+	return []string{string(EnvModeInherit), string(EnvModeClean), string(EnvModeAllowlist)}
+}
+
+// environ returns the calling environment filtered according to the mode.
+// An empty mode behaves like EnvModeInherit.
+func (m EnvMode) environ(allowlist []string) []string {
+	var keep []string
+	switch m {
+	case EnvModeClean:
+		keep = baselineEnv
+	case EnvModeAllowlist:
+		keep = append(append([]string{}, baselineEnv...), allowlist...)
+	default:
+		return os.Environ()
+	}
+	var env []string
+	for _, key := range keep {
+		if value, ok := os.LookupEnv(key); ok {
+			env = append(env, fmt.Sprintf("%s=%s", key, value))
+		}
+	}
+	return mergeEnv(nil, env)
+}
+
+// envList converts an env mapping into sorted KEY=VALUE pairs.
+func envList(env map[string]string) []string {
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", k, env[k]))
+	}
+	return pairs
+}
+
+// mergeEnv overlays KEY=VALUE pairs onto a base environment. Keys that
+// already exist are replaced in place rather than duplicated.
+func mergeEnv(base []string, overlays ...[]string) []string {
+	merged := make([]string, 0, len(base))
+	index := make(map[string]int, len(base))
+	add := func(pair string) {
+		key, _, _ := strings.Cut(pair, "=")
+		if idx, exists := index[key]; exists {
+			merged[idx] = pair
+			return
+		}
+		index[key] = len(merged)
+		merged = append(merged, pair)
+	}
+	for _, pair := range base {
+		add(pair)
+	}
+	for _, overlay := range overlays {
+		for _, pair := range overlay {
+			add(pair)
+		}
+	}
+	return merged
+}
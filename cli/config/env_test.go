@@ -0,0 +1,58 @@
+package config
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeEnvReplacesExistingKeys(t *testing.T) {
+	merged := mergeEnv([]string{"A=1", "B=2"}, []string{"B=3", "C=4"}, []string{"A=5"})
+	assert.Equal(t, []string{"A=5", "B=3", "C=4"}, merged)
+}
+
+func TestEnvModeEnviron(t *testing.T) {
+	t.Setenv("PATH", "/usr/bin")
+	t.Setenv("HOME", "/home/dev")
+	t.Setenv("SECRET_TOKEN", "hunter2")
+	t.Setenv("GOFLAGS", "-mod=mod")
+
+	assert.Subset(t, EnvMode("").environ(nil), []string{"SECRET_TOKEN=hunter2", "GOFLAGS=-mod=mod"})
+	assert.Equal(t, []string{"PATH=/usr/bin", "HOME=/home/dev"}, EnvModeClean.environ(nil))
+	assert.Equal(t, []string{"PATH=/usr/bin", "HOME=/home/dev", "GOFLAGS=-mod=mod"},
+		EnvModeAllowlist.environ([]string{"GOFLAGS", "UNSET_VARIABLE"}))
+}
+
+func TestOperationRunEnvPropagation(t *testing.T) {
+	t.Setenv("PATH", "/usr/bin")
+	t.Setenv("HOME", "/home/dev")
+	t.Setenv("SECRET_TOKEN", "hunter2")
+
+	exec := &fakeExecutor{}
+	op := Operation{
+		EnvMode: EnvModeClean,
+		Env:     map[string]string{"CGO_ENABLED": "0", "HOME": "/tmp/build"},
+		Steps: []Step{
+			{Run: "go build"},
+			{Run: "go test", Env: map[string]string{"CGO_ENABLED": "1"}},
+		},
+	}
+	require.NoError(t, op.Run(context.Background(), exec))
+	assert.Equal(t, []string{"PATH=/usr/bin", "HOME=/tmp/build", "CGO_ENABLED=0"}, withoutRunFiles(exec.received[0].Env))
+	assert.Equal(t, []string{"PATH=/usr/bin", "HOME=/tmp/build", "CGO_ENABLED=1"}, withoutRunFiles(exec.received[1].Env))
+}
+
+// withoutRunFiles drops the variables pointing the steps to their output
+// and environment files.
+func withoutRunFiles(env []string) []string {
+	var kept []string
+	for _, pair := range env {
+		if !strings.HasPrefix(pair, "OPSRUNNER_") {
+			kept = append(kept, pair)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,136 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+const (
+	// EnvFileEnvVar names the environment variable holding the path of the
+	// file steps can append KEY=VALUE lines to, setting variables for the
+	// later steps of the operation.
+	EnvFileEnvVar = "OPSRUNNER_ENV"
+	// PathFileEnvVar names the environment variable holding the path of the
+	// file steps can append directories to, one per line, adding them to
+	// the front of the PATH of the later steps of the operation.
+	PathFileEnvVar = "OPSRUNNER_PATH"
+)
+
+// envFiles tracks the files the steps of an operation write environment
+// changes to. The files are read from where the previous read stopped, so
+// that relative directories resolve against the step that added them.
+type envFiles struct {
+	dir        string
+	envOffset  int64
+	pathOffset int64
+	vars       []string
+	// paths holds the added directories, the most recent first.
+	paths []string
+}
+
+// newEnvFiles creates empty environment files in a temporary directory,
+// which Close removes.
+func newEnvFiles() (*envFiles, error) {
+	dir, err := os.MkdirTemp("", "opsrunner-env-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create environment files: %w", err)
+	}
+	files := &envFiles{dir: dir}
+	for _, path := range []string{files.envFile(), files.pathFile()} {
+		if err := os.WriteFile(path, nil, 0o600); err != nil {
+			_ = files.Close()
+			return nil, fmt.Errorf("failed to create environment files: %w", err)
+		}
+	}
+	return files, nil
+}
+
+func (f *envFiles) envFile() string {
+	return filepath.Join(f.dir, "env")
+}
+
+func (f *envFiles) pathFile() string {
+	return filepath.Join(f.dir, "path")
+}
+
+// environ returns the variables pointing the steps to the files.
+func (f *envFiles) environ() []string {
+	return []string{EnvFileEnvVar + "=" + f.envFile(), PathFileEnvVar + "=" + f.pathFile()}
+}
+
+// apply returns the environment with the changes of earlier steps applied.
+func (f *envFiles) apply(env []string) []string {
+	env = mergeEnv(env, f.vars)
+	if len(f.paths) == 0 {
+		return env
+	}
+	path := strings.Join(f.paths, string(os.PathListSeparator))
+	for _, pair := range env {
+		if value, ok := strings.CutPrefix(pair, "PATH="); ok && value != "" {
+			path += string(os.PathListSeparator) + value
+		}
+	}
+	return mergeEnv(env, []string{"PATH=" + path})
+}
+
+// update reads the changes written since the last update. Relative
+// directories are resolved against dir, the working directory of the step
+// that wrote them.
+func (f *envFiles) update(dir string) error {
+	content, err := readFrom(f.envFile(), &f.envOffset)
+	if err != nil {
+		return err
+	}
+	vars, err := parseOutputs(content)
+	if err != nil {
+		return fmt.Errorf("invalid %s file: %w", EnvFileEnvVar, err)
+	}
+	f.vars = mergeEnv(f.vars, envList(vars))
+
+	content, err = readFrom(f.pathFile(), &f.pathOffset)
+	if err != nil {
+		return err
+	}
+	for _, line := range strings.Split(content, "\n") {
+		path := strings.TrimSpace(line)
+		if path == "" {
+			continue
+		}
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		if path, err = filepath.Abs(path); err != nil {
+			return fmt.Errorf("invalid %s entry '%s': %w", PathFileEnvVar, line, err)
+		}
+		f.paths = slices.Insert(slices.DeleteFunc(f.paths, func(p string) bool { return p == path }), 0, path)
+	}
+	return nil
+}
+
+// Close removes the files.
+func (f *envFiles) Close() error {
+	return os.RemoveAll(f.dir)
+}
+
+// readFrom returns the content of the file after the offset, and moves the
+// offset to its end.
+func readFrom(path string, offset *int64) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read environment file: %w", err)
+	}
+	defer file.Close()
+	if _, err := file.Seek(*offset, io.SeekStart); err != nil {
+		return "", fmt.Errorf("failed to read environment file: %w", err)
+	}
+	content, err := io.ReadAll(file)
+	if err != nil {
+		return "", fmt.Errorf("failed to read environment file: %w", err)
+	}
+	*offset += int64(len(content))
+	return string(content), nil
+}
@@ -0,0 +1,69 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gtithub.com/jgfranco17/opsrunner/cli/executor"
+)
+
+func TestEnvFilesApply(t *testing.T) {
+	files, err := newEnvFiles()
+	require.NoError(t, err)
+	defer files.Close()
+
+	base := []string{"PATH=/usr/bin", "HOME=/home/dev"}
+	assert.Equal(t, base, files.apply(base))
+
+	require.NoError(t, os.WriteFile(files.envFile(), []byte("GOFLAGS=-mod=mod\nHOME=/tmp\n"), 0o600))
+	require.NoError(t, os.WriteFile(files.pathFile(), []byte("/opt/go/bin\nbin\n"), 0o600))
+	require.NoError(t, files.update("tools"))
+
+	bin, err := filepath.Abs(filepath.Join("tools", "bin"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"PATH=" + bin + ":/opt/go/bin:/usr/bin", "HOME=/tmp", "GOFLAGS=-mod=mod"}, files.apply(base))
+
+	// Only the lines added since the last update are read again.
+	file, err := os.OpenFile(files.pathFile(), os.O_APPEND|os.O_WRONLY, 0o600)
+	require.NoError(t, err)
+	_, err = file.WriteString("/opt/go/bin\n")
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+	require.NoError(t, files.update(""))
+	assert.Equal(t, []string{"/opt/go/bin", bin}, files.paths)
+}
+
+func TestEnvFilesInvalidLine(t *testing.T) {
+	files, err := newEnvFiles()
+	require.NoError(t, err)
+	defer files.Close()
+
+	require.NoError(t, os.WriteFile(files.envFile(), []byte("export GOFLAGS\n"), 0o600))
+	assert.EqualError(t, files.update(""), "invalid OPSRUNNER_ENV file: invalid output 'export GOFLAGS': expected key=value")
+}
+
+func TestOperationRunCarriesEnvChanges(t *testing.T) {
+	dir := t.TempDir()
+	tool := filepath.Join(dir, "bin", "greet")
+	require.NoError(t, os.MkdirAll(filepath.Dir(tool), 0o755))
+	require.NoError(t, os.WriteFile(tool, []byte("#!/bin/sh\necho \"hello $GREETING\"\n"), 0o755))
+	result := filepath.Join(dir, "result")
+
+	op := Operation{
+		Steps: []Step{
+			{Run: `echo "GREETING=world" >> "$OPSRUNNER_ENV"`},
+			{Run: `echo bin >> "$OPSRUNNER_PATH"`, Dir: dir},
+			{Run: "greet > " + result},
+		},
+	}
+	require.NoError(t, op.Run(context.Background(), &executor.DefaultExecutor{}))
+
+	content, err := os.ReadFile(result)
+	require.NoError(t, err)
+	assert.Equal(t, "hello world\n", string(content))
+}
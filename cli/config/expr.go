@@ -0,0 +1,460 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"unicode"
+)
+
+// Conditions are small boolean expressions deciding whether a step or an
+// operation runs, e.g. `os == "linux" && env.CI == "true"`. They support
+// string and boolean literals, the == and != comparisons, the !, && and
+// || operators, parentheses, and the following names:
+//
+//	os, arch             the operating system and architecture
+//	env.NAME             the value of an environment variable, or ""
+//	file_exists(path)    whether the path exists
+//	success()            no earlier step of the operation failed
+//	failure()            an earlier step of the operation failed
+//	always()             always true
+//
+// Evaluation has no side effects beyond checking for files.
+
+// errOperationStatus is reported for operation conditions checking the
+// outcome of steps, which only steps have.
+var errOperationStatus = errors.New("success(), failure() and always() only apply to steps; " +
+	"use hooks.on_failure or hooks.always to run steps after a failure")
+
+// exprContext holds the state a condition is evaluated against.
+type exprContext struct {
+	lookupEnv func(string) (string, bool)
+	failed    bool
+}
+
+func newExprContext(failed bool) *exprContext {
+	return &exprContext{
+		lookupEnv: os.LookupEnv,
+		failed:    failed,
+	}
+}
+
+// evalCondition parses and evaluates the expression. An empty expression
+// is true.
+func evalCondition(expression string, ectx *exprContext) (bool, error) {
+	if strings.TrimSpace(expression) == "" {
+		return true, nil
+	}
+	node, err := parseExpr(expression)
+	if err != nil {
+		return false, err
+	}
+	value, err := node.eval(ectx)
+	if err != nil {
+		return false, fmt.Errorf("failed to evaluate condition '%s': %w", expression, err)
+	}
+	return truthy(value), nil
+}
+
+// usesStatus reports whether the expression checks the outcome of earlier
+// steps through success(), failure() or always().
+func usesStatus(expression string) bool {
+	node, err := parseExpr(expression)
+	if err != nil {
+		return false
+	}
+	found := false
+	walkExpr(node, func(n exprNode) {
+		if call, ok := n.(*callNode); ok {
+			switch call.name {
+			case "success", "failure", "always":
+				found = true
+			}
+		}
+	})
+	return found
+}
+
+// exprNode is a node of a parsed condition. Values are strings or bools.
+type exprNode interface {
+	eval(ectx *exprContext) (any, error)
+}
+
+type literalNode struct {
+	value any
+}
+
+type variableNode struct {
+	name string
+	key  string
+}
+
+type callNode struct {
+	name string
+	args []exprNode
+}
+
+type unaryNode struct {
+	operand exprNode
+}
+
+type binaryNode struct {
+	operator    string
+	left, right exprNode
+}
+
+func (n *literalNode) eval(ectx *exprContext) (any, error) {
+	return n.value, nil
+}
+
+func (n *variableNode) eval(ectx *exprContext) (any, error) {
+	switch n.name {
+	case "os":
+		return runtime.GOOS, nil
+	case "arch":
+		return runtime.GOARCH, nil
+	case "env":
+		value, _ := ectx.lookupEnv(n.key)
+		return value, nil
+	}
+	return nil, fmt.Errorf("unknown variable '%s'", n.name)
+}
+
+func (n *callNode) eval(ectx *exprContext) (any, error) {
+	switch n.name {
+	case "success":
+		return !ectx.failed, nil
+	case "failure":
+		return ectx.failed, nil
+	case "always":
+		return true, nil
+	case "file_exists":
+		path, err := n.args[0].eval(ectx)
+		if err != nil {
+			return nil, err
+		}
+		_, statErr := os.Stat(toString(path))
+		return statErr == nil, nil
+	}
+	return nil, fmt.Errorf("unknown function '%s'", n.name)
+}
+
+func (n *unaryNode) eval(ectx *exprContext) (any, error) {
+	value, err := n.operand.eval(ectx)
+	if err != nil {
+		return nil, err
+	}
+	return !truthy(value), nil
+}
+
+func (n *binaryNode) eval(ectx *exprContext) (any, error) {
+	left, err := n.left.eval(ectx)
+	if err != nil {
+		return nil, err
+	}
+	// Logical operators short-circuit.
+	switch n.operator {
+	case "&&":
+		if !truthy(left) {
+			return false, nil
+		}
+	case "||":
+		if truthy(left) {
+			return true, nil
+		}
+	}
+	right, err := n.right.eval(ectx)
+	if err != nil {
+		return nil, err
+	}
+	switch n.operator {
+	case "&&", "||":
+		return truthy(right), nil
+	case "==":
+		return toString(left) == toString(right), nil
+	case "!=":
+		return toString(left) != toString(right), nil
+	}
+	return nil, fmt.Errorf("unknown operator '%s'", n.operator)
+}
+
+func walkExpr(node exprNode, visit func(exprNode)) {
+	visit(node)
+	switch n := node.(type) {
+	case *callNode:
+		for _, arg := range n.args {
+			walkExpr(arg, visit)
+		}
+	case *unaryNode:
+		walkExpr(n.operand, visit)
+	case *binaryNode:
+		walkExpr(n.left, visit)
+		walkExpr(n.right, visit)
+	}
+}
+
+func truthy(value any) bool {
+	switch v := value.(type) {
+	case bool:
+		return v
+	case string:
+		return v != ""
+	}
+	return false
+}
+
+func toString(value any) string {
+	switch v := value.(type) {
+	case bool:
+		if v {
+			return "true"
+		}
+		return "false"
+	case string:
+		return v
+	}
+	return ""
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenString
+	tokenOperator
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+	pos   int
+}
+
+func tokenize(expression string) ([]token, error) {
+	var tokens []token
+	runes := []rune(expression)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '"' || r == '\'':
+			start := i
+			i++
+			var sb strings.Builder
+			for i < len(runes) && runes[i] != r {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+				}
+				sb.WriteRune(runes[i])
+				i++
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("unterminated string at position %d", start+1)
+			}
+			i++
+			tokens = append(tokens, token{kind: tokenString, value: sb.String(), pos: start})
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, value: string(runes[start:i]), pos: start})
+		default:
+			start := i
+			if i+1 < len(runes) {
+				pair := string(runes[i : i+2])
+				if pair == "==" || pair == "!=" || pair == "&&" || pair == "||" {
+					tokens = append(tokens, token{kind: tokenOperator, value: pair, pos: start})
+					i += 2
+					continue
+				}
+			}
+			if strings.ContainsRune("!().,", r) {
+				tokens = append(tokens, token{kind: tokenOperator, value: string(r), pos: start})
+				i++
+				continue
+			}
+			return nil, fmt.Errorf("unexpected character '%c' at position %d", r, start+1)
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(runes)}), nil
+}
+
+type exprParser struct {
+	tokens []token
+	pos    int
+}
+
+// parseExpr parses a condition, checking that it only refers to known
+// variables and functions.
+func parseExpr(expression string) (exprNode, error) {
+	tokens, err := tokenize(expression)
+	if err != nil {
+		return nil, fmt.Errorf("invalid condition '%s': %w", expression, err)
+	}
+	parser := &exprParser{tokens: tokens}
+	node, err := parser.parseOr()
+	if err == nil && parser.peek().kind != tokenEOF {
+		err = parser.unexpected()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid condition '%s': %w", expression, err)
+	}
+	return node, nil
+}
+
+func (p *exprParser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *exprParser) accept(operator string) bool {
+	if tok := p.peek(); tok.kind == tokenOperator && tok.value == operator {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *exprParser) expect(operator string) error {
+	if !p.accept(operator) {
+		return p.unexpected()
+	}
+	return nil
+}
+
+func (p *exprParser) unexpected() error {
+	tok := p.peek()
+	if tok.kind == tokenEOF {
+		return fmt.Errorf("unexpected end of expression")
+	}
+	return fmt.Errorf("unexpected '%s' at position %d", tok.value, tok.pos+1)
+}
+
+func (p *exprParser) parseOr() (exprNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{operator: "||", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseAnd() (exprNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("&&") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{operator: "&&", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseUnary() (exprNode, error) {
+	if p.accept("!") {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryNode{operand: operand}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *exprParser) parseComparison() (exprNode, error) {
+	left, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for _, operator := range []string{"==", "!="} {
+		if p.accept(operator) {
+			right, err := p.parsePrimary()
+			if err != nil {
+				return nil, err
+			}
+			return &binaryNode{operator: operator, left: left, right: right}, nil
+		}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parsePrimary() (exprNode, error) {
+	if p.accept("(") {
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		return node, p.expect(")")
+	}
+	tok := p.peek()
+	switch tok.kind {
+	case tokenString:
+		p.pos++
+		return &literalNode{value: tok.value}, nil
+	case tokenIdent:
+		p.pos++
+		switch tok.value {
+		case "true", "false":
+			return &literalNode{value: tok.value == "true"}, nil
+		case "os", "arch":
+			return &variableNode{name: tok.value}, nil
+		case "env":
+			if err := p.expect("."); err != nil {
+				return nil, err
+			}
+			key := p.peek()
+			if key.kind != tokenIdent {
+				return nil, p.unexpected()
+			}
+			p.pos++
+			return &variableNode{name: "env", key: key.value}, nil
+		}
+		if p.accept("(") {
+			return p.parseCall(tok)
+		}
+		return nil, fmt.Errorf("unknown variable '%s' at position %d", tok.value, tok.pos+1)
+	}
+	return nil, p.unexpected()
+}
+
+func (p *exprParser) parseCall(name token) (exprNode, error) {
+	var args []exprNode
+	if !p.accept(")") {
+		for {
+			arg, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+			if p.accept(")") {
+				break
+			}
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+	}
+	arity := map[string]int{"success": 0, "failure": 0, "always": 0, "file_exists": 1}
+	expected, known := arity[name.value]
+	if !known {
+		return nil, fmt.Errorf("unknown function '%s' at position %d", name.value, name.pos+1)
+	}
+	if len(args) != expected {
+		return nil, fmt.Errorf("function '%s' expects %d argument(s), got %d", name.value, expected, len(args))
+	}
+	return &callNode{name: name.value, args: args}, nil
+}
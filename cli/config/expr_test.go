@@ -0,0 +1,118 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvalCondition(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "go.mod")
+	require.NoError(t, os.WriteFile(file, nil, 0o644))
+	ectx := &exprContext{
+		lookupEnv: func(key string) (string, bool) {
+			env := map[string]string{"CI": "true", "TARGET": "prod"}
+			value, ok := env[key]
+			return value, ok
+		},
+	}
+
+	tests := []struct {
+		expression string
+		expected   bool
+	}{
+		{"", true},
+		{"true", true},
+		{"!true", false},
+		{`os == "` + runtime.GOOS + `"`, true},
+		{`arch != '` + runtime.GOARCH + `'`, false},
+		{`env.CI == "true"`, true},
+		{`env.MISSING`, false},
+		{`env.CI && env.TARGET == "staging"`, false},
+		{`env.TARGET == "staging" || env.TARGET == "prod"`, true},
+		{`!(os == "plan9") && env.CI`, true},
+		{`file_exists("` + file + `")`, true},
+		{`file_exists("missing.txt")`, false},
+		{`success()`, true},
+		{`failure()`, false},
+		{`always()`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			result, err := evalCondition(tt.expression, ectx)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestParseExprErrors(t *testing.T) {
+	tests := map[string]string{
+		`os ==`:               "invalid condition 'os ==': unexpected end of expression",
+		`(os == "linux"`:      "invalid condition '(os == \"linux\"': unexpected end of expression",
+		`os == "linux`:        "invalid condition 'os == \"linux': unterminated string at position 7",
+		`platform == "linux"`: "invalid condition 'platform == \"linux\"': unknown variable 'platform' at position 1",
+		`exists("go.mod")`:    "invalid condition 'exists(\"go.mod\")': unknown function 'exists' at position 1",
+		`failure(1)`:          "invalid condition 'failure(1)': unexpected character '1' at position 9",
+		`file_exists()`:       "invalid condition 'file_exists()': function 'file_exists' expects 1 argument(s), got 0",
+		`os "linux"`:          "invalid condition 'os \"linux\"': unexpected 'linux' at position 4",
+	}
+	for expression, expected := range tests {
+		t.Run(expression, func(t *testing.T) {
+			_, err := parseExpr(expression)
+			assert.EqualError(t, err, expected)
+		})
+	}
+}
+
+func TestUsesStatus(t *testing.T) {
+	assert.True(t, usesStatus("failure()"))
+	assert.True(t, usesStatus(`always() && os == "linux"`))
+	assert.False(t, usesStatus(`os == "linux"`))
+	assert.False(t, usesStatus(""))
+}
+
+func TestOperationRunSkipsStepsByCondition(t *testing.T) {
+	exec := &fakeExecutor{}
+	op := Operation{
+		Steps: []Step{
+			{Run: "make"},
+			{Run: "make windows", If: `os == "windows" && os != "` + runtime.GOOS + `"`},
+			{Run: "make report", If: "failure()"},
+		},
+	}
+	require.NoError(t, op.Run(context.Background(), exec))
+	assert.Equal(t, []string{"make"}, exec.commands)
+}
+
+func TestOperationRunFailureSteps(t *testing.T) {
+	exec := &fakeExecutor{exitCodes: map[string]int{"make test": 1}}
+	op := Operation{
+		FailFast: true,
+		Steps: []Step{
+			{Run: "make test"},
+			{Run: "make lint"},
+			{Run: "make report", If: "failure()"},
+			{Run: "make clean", If: "always()"},
+			{Run: "make publish", If: "success()"},
+		},
+	}
+	err := op.Run(context.Background(), exec)
+	assert.EqualError(t, err, "error while running 'make test' (exit code 1, 1 attempt(s))")
+	assert.Equal(t, []string{"make test", "make report", "make clean"}, exec.commands)
+}
+
+func TestOperationRunSkippedByCondition(t *testing.T) {
+	exec := &fakeExecutor{}
+	op := Operation{
+		If:    `env.OPSRUNNER_TEST_UNSET_VARIABLE == "1"`,
+		Steps: []Step{{Run: "make"}},
+	}
+	require.NoError(t, op.Run(context.Background(), exec))
+	assert.Empty(t, exec.commands)
+}
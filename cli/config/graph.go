@@ -0,0 +1,131 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"gtithub.com/jgfranco17/opsrunner/cli/logging"
+)
+
+// Node is a single unit of work in a dependency graph.
+type Node struct {
+	Name      string
+	DependsOn []string
+	Run       func(ctx context.Context) error
+}
+
+// CycleError is returned when the dependencies of a node loop back
+// onto themselves. Path lists the nodes forming the cycle, starting
+// and ending with the same node.
+type CycleError struct {
+	Path []string
+}
+
+func (e *CycleError) Error() string {
+	return fmt.Sprintf("dependency cycle detected: %s", strings.Join(e.Path, " -> "))
+}
+
+// Graph is a directed acyclic graph of nodes, executed in dependency order.
+type Graph struct {
+	nodes map[string]*Node
+}
+
+// NewGraph creates an empty dependency graph.
+func NewGraph() *Graph {
+	return &Graph{
+		nodes: make(map[string]*Node),
+	}
+}
+
+// Add registers a node in the graph. Node names must be unique.
+func (g *Graph) Add(node Node) error {
+	if node.Name == "" {
+		return fmt.Errorf("node name cannot be empty")
+	}
+	if _, exists := g.nodes[node.Name]; exists {
+		return fmt.Errorf("duplicate node '%s' in dependency graph", node.Name)
+	}
+	g.nodes[node.Name] = &node
+	return nil
+}
+
+// Resolve returns the names of the targets and all of their transitive
+// dependencies in the order they must be executed. Every node appears
+// exactly once, after all of its dependencies.
+func (g *Graph) Resolve(targets ...string) ([]string, error) {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int, len(g.nodes))
+	var order []string
+	var path []string
+
+	var visit func(name string, parent string) error
+	visit = func(name string, parent string) error {
+		node, ok := g.nodes[name]
+		if !ok {
+			if parent == "" {
+				return fmt.Errorf("node '%s' is not defined", name)
+			}
+			return fmt.Errorf("'%s' depends on undefined node '%s'", parent, name)
+		}
+		switch state[name] {
+		case visited:
+			return nil
+		case visiting:
+			start := 0
+			for idx, entry := range path {
+				if entry == name {
+					start = idx
+					break
+				}
+			}
+			cycle := append([]string{}, path[start:]...)
+			return &CycleError{Path: append(cycle, name)}
+		}
+		state[name] = visiting
+		path = append(path, name)
+		for _, dep := range node.DependsOn {
+			if err := visit(dep, name); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = visited
+		order = append(order, name)
+		return nil
+	}
+
+	for _, target := range targets {
+		if err := visit(target, ""); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// Execute runs the targets and their dependencies in topological order,
+// stopping at the first node that fails.
+func (g *Graph) Execute(ctx context.Context, targets ...string) error {
+	logger := logging.FromContext(ctx)
+
+	order, err := g.Resolve(targets...)
+	if err != nil {
+		return err
+	}
+	logger.Debugf("Execution order: %s", strings.Join(order, " -> "))
+	for _, name := range order {
+		node := g.nodes[name]
+		if node.Run == nil {
+			continue
+		}
+		logger.Tracef("Running node '%s'", name)
+		if err := node.Run(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
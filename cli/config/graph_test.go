@@ -0,0 +1,90 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestGraph(t *testing.T, deps map[string][]string, ran *[]string) *Graph {
+	t.Helper()
+	graph := NewGraph()
+	for name, dependsOn := range deps {
+		err := graph.Add(Node{
+			Name:      name,
+			DependsOn: dependsOn,
+			Run: func(ctx context.Context) error {
+				*ran = append(*ran, name)
+				return nil
+			},
+		})
+		require.NoError(t, err)
+	}
+	return graph
+}
+
+func TestGraphExecuteRunsDependenciesOnce(t *testing.T) {
+	var ran []string
+	graph := newTestGraph(t, map[string][]string{
+		"release": {"test", "build"},
+		"test":    {"build"},
+		"build":   {"install"},
+		"install": nil,
+		"unused":  nil,
+	}, &ran)
+
+	err := graph.Execute(context.Background(), "release")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"install", "build", "test", "release"}, ran)
+}
+
+func TestGraphResolveFail_Cycle(t *testing.T) {
+	var ran []string
+	graph := newTestGraph(t, map[string][]string{
+		"a": {"b"},
+		"b": {"c"},
+		"c": {"a"},
+	}, &ran)
+
+	_, err := graph.Resolve("a")
+	var cycleErr *CycleError
+	require.True(t, errors.As(err, &cycleErr))
+	assert.Equal(t, []string{"a", "b", "c", "a"}, cycleErr.Path)
+	assert.EqualError(t, err, "dependency cycle detected: a -> b -> c -> a")
+}
+
+func TestGraphResolveFail_UndefinedDependency(t *testing.T) {
+	var ran []string
+	graph := newTestGraph(t, map[string][]string{
+		"deploy": {"package"},
+	}, &ran)
+
+	_, err := graph.Resolve("deploy")
+	assert.EqualError(t, err, "'deploy' depends on undefined node 'package'")
+}
+
+func TestGraphAddFail_Duplicate(t *testing.T) {
+	graph := NewGraph()
+	require.NoError(t, graph.Add(Node{Name: "lint"}))
+	assert.ErrorContains(t, graph.Add(Node{Name: "lint"}), "duplicate node 'lint'")
+}
+
+func TestGraphExecuteStopsOnFailure(t *testing.T) {
+	var ran []string
+	graph := NewGraph()
+	require.NoError(t, graph.Add(Node{Name: "first", Run: func(ctx context.Context) error {
+		ran = append(ran, "first")
+		return errors.New("boom")
+	}}))
+	require.NoError(t, graph.Add(Node{Name: "second", DependsOn: []string{"first"}, Run: func(ctx context.Context) error {
+		ran = append(ran, "second")
+		return nil
+	}}))
+
+	err := graph.Execute(context.Background(), "second")
+	assert.EqualError(t, err, "boom")
+	assert.Equal(t, []string{"first"}, ran)
+}
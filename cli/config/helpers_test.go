@@ -0,0 +1,27 @@
+package config
+
+import (
+	"context"
+
+	"gtithub.com/jgfranco17/opsrunner/cli/executor"
+)
+
+// fakeExecutor records every command it receives and returns the
+// configured exit code for it (zero by default). Commands listed in
+// exitSequences return the next code of their sequence on every call.
+type fakeExecutor struct {
+	commands      []string
+	received      []executor.Command
+	exitCodes     map[string]int
+	exitSequences map[string][]int
+}
+
+func (f *fakeExecutor) Exec(ctx context.Context, command executor.Command) (executor.Result, error) {
+	f.commands = append(f.commands, command.Run)
+	f.received = append(f.received, command)
+	if sequence := f.exitSequences[command.Run]; len(sequence) > 0 {
+		f.exitSequences[command.Run] = sequence[1:]
+		return executor.Result{ExitCode: sequence[0]}, nil
+	}
+	return executor.Result{ExitCode: f.exitCodes[command.Run]}, nil
+}
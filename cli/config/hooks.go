@@ -0,0 +1,86 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"gtithub.com/jgfranco17/opsrunner/cli/logging"
+)
+
+// Hooks are operations run at fixed points around a build, a task or an
+// operation. Before runs first and a failure there skips the main steps.
+// After runs once the main steps succeeded, OnFailure once they or any
+// earlier hook failed, and Always runs last whatever the outcome.
+//
+// OnFailure and Always also run when the run is cancelled or times out,
+// so that cleanup is not skipped; they are not interrupted themselves and
+// should set their own timeout when they might hang.
+type Hooks struct {
+	Before    *Operation `yaml:"before,omitempty" desc:"Operation run before the main steps"`
+	After     *Operation `yaml:"after,omitempty" desc:"Operation run after the main steps succeeded"`
+	OnFailure *Operation `yaml:"on_failure,omitempty" desc:"Operation run when the main steps or a hook failed, or the run was cancelled"`
+	Always    *Operation `yaml:"always,omitempty" desc:"Operation run last, whatever the outcome"`
+}
+
+// hook is a hook operation together with its YAML key.
+type hook struct {
+	Name      string
+	Operation *Operation
+}
+
+// hooks returns the defined hooks in the order they can run.
+func (h *Hooks) hooks() []hook {
+	if h == nil {
+		return nil
+	}
+	var defined []hook
+	for _, candidate := range []hook{
+		{"before", h.Before},
+		{"after", h.After},
+		{"on_failure", h.OnFailure},
+		{"always", h.Always},
+	} {
+		if candidate.Operation != nil {
+			defined = append(defined, candidate)
+		}
+	}
+	return defined
+}
+
+// around runs the main function surrounded by the hooks. Errors of the
+// main function and of the hooks are all returned.
+func (h *Hooks) around(ctx context.Context, shellExecutor ShellExecutor, run func(context.Context) error) error {
+	if h == nil {
+		return run(ctx)
+	}
+	var err error
+	if h.Before != nil {
+		err = h.run(ctx, shellExecutor, "before", h.Before)
+	}
+	if err == nil {
+		err = run(ctx)
+	}
+	if err == nil && h.After != nil {
+		err = h.run(ctx, shellExecutor, "after", h.After)
+	}
+
+	// Cleanup hooks must run even when the run was cancelled.
+	cleanupCtx := context.WithoutCancel(ctx)
+	if err != nil && h.OnFailure != nil {
+		err = errors.Join(err, h.run(cleanupCtx, shellExecutor, "on_failure", h.OnFailure))
+	}
+	if h.Always != nil {
+		err = errors.Join(err, h.run(cleanupCtx, shellExecutor, "always", h.Always))
+	}
+	return err
+}
+
+func (h *Hooks) run(ctx context.Context, shellExecutor ShellExecutor, name string, op *Operation) error {
+	logger := logging.FromContext(ctx)
+	logger.Infof("Running '%s' hook", name)
+	if err := op.Run(ctx, shellExecutor); err != nil {
+		return fmt.Errorf("'%s' hook failed: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"gtithub.com/jgfranco17/opsrunner/cli/executor"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func testHooks() *Hooks {
+	return &Hooks{
+		Before:    &Operation{Steps: []Step{{Run: "start db"}}},
+		After:     &Operation{Steps: []Step{{Run: "report"}}},
+		OnFailure: &Operation{Steps: []Step{{Run: "dump logs"}}},
+		Always:    &Operation{Steps: []Step{{Run: "stop db"}}},
+	}
+}
+
+func TestHooksUnmarshal(t *testing.T) {
+	content := `
+steps: [make test]
+hooks:
+  before:
+    steps: [docker compose up -d]
+  always:
+    steps: [docker compose down]
+`
+	var op Operation
+	require.NoError(t, yaml.Unmarshal([]byte(content), &op))
+	require.NotNil(t, op.Hooks)
+	assert.Equal(t, []Step{{Run: "docker compose up -d"}}, op.Hooks.Before.Steps)
+	assert.Equal(t, []Step{{Run: "docker compose down"}}, op.Hooks.Always.Steps)
+	assert.Nil(t, op.Hooks.After)
+	assert.Nil(t, op.Hooks.OnFailure)
+}
+
+func TestOperationHooksOnSuccess(t *testing.T) {
+	exec := &fakeExecutor{}
+	op := Operation{Hooks: testHooks(), Steps: []Step{{Run: "make test"}}}
+	require.NoError(t, op.Run(context.Background(), exec))
+	assert.Equal(t, []string{"start db", "make test", "report", "stop db"}, exec.commands)
+}
+
+func TestOperationHooksOnFailure(t *testing.T) {
+	exec := &fakeExecutor{exitCodes: map[string]int{"make test": 1}}
+	op := Operation{FailFast: true, Hooks: testHooks(), Steps: []Step{{Run: "make test"}}}
+	err := op.Run(context.Background(), exec)
+	assert.EqualError(t, err, "error while running 'make test' (exit code 1, 1 attempt(s))")
+	assert.Equal(t, []string{"start db", "make test", "dump logs", "stop db"}, exec.commands)
+}
+
+func TestOperationHooksBeforeFailureSkipsSteps(t *testing.T) {
+	exec := &fakeExecutor{exitCodes: map[string]int{"start db": 1}}
+	op := Operation{Hooks: testHooks(), Steps: []Step{{Run: "make test"}}}
+	err := op.Run(context.Background(), exec)
+	assert.ErrorContains(t, err, "'before' hook failed")
+	assert.Equal(t, []string{"start db", "dump logs", "stop db"}, exec.commands)
+}
+
+func TestOperationHooksFailuresAreJoined(t *testing.T) {
+	exec := &fakeExecutor{exitCodes: map[string]int{"make test": 1, "stop db": 1}}
+	op := Operation{FailFast: true, Hooks: testHooks(), Steps: []Step{{Run: "make test"}}}
+	err := op.Run(context.Background(), exec)
+	assert.ErrorContains(t, err, "error while running 'make test'")
+	assert.ErrorContains(t, err, "'always' hook failed")
+}
+
+func TestOperationHooksRunOnCancellation(t *testing.T) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	cancel(&executor.InterruptError{})
+	exec := &fakeExecutor{}
+	op := Operation{Hooks: testHooks(), Steps: []Step{{Run: "make test"}}}
+
+	err := op.Run(ctx, exec)
+	var interrupt *executor.InterruptError
+	assert.True(t, errors.As(err, &interrupt))
+	assert.Equal(t, []string{"dump logs", "stop db"}, exec.commands)
+}
+
+func TestBuildRunsProjectHooks(t *testing.T) {
+	exec := &fakeExecutor{exitCodes: map[string]int{"go build": 1}}
+	cfg := &ProjectDefinition{
+		Codebase: Codebase{
+			Install: Operation{Steps: []Step{{Run: "go mod download"}}},
+			Build:   Operation{FailFast: true, Steps: []Step{{Run: "go build"}}},
+		},
+		Hooks: testHooks(),
+	}
+	err := Build(context.Background(), exec, cfg, nil)
+	assert.ErrorContains(t, err, "failed to run build steps")
+	assert.Equal(t, []string{"start db", "go mod download", "go build", "dump logs", "stop db"}, exec.commands)
+}
@@ -0,0 +1,273 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Definition files can include other files with a top-level include list
+// of paths or glob patterns, relative to the including file. Included
+// files are merged in order, and the including file is merged last:
+//
+//   - mappings are merged key by key, recursively;
+//   - every other value, including lists such as steps, replaces the
+//     included value as a whole.
+//
+// Included files can include further files themselves.
+
+// loader reads definition files and resolves their includes, recording
+// the file every included node comes from.
+type loader struct {
+	origins map[*yaml.Node]string
+	stack   []string
+}
+
+func newLoader() *loader {
+	return &loader{origins: make(map[*yaml.Node]string)}
+}
+
+// load decodes a definition file, resolving its includes relative to dir
+// and applying the selected profile.
+func (l *loader) load(r io.Reader, dir string, opts *LoadOptions) (*ProjectDefinition, error) {
+	if opts == nil {
+		opts = &LoadOptions{}
+	}
+	var document yaml.Node
+	decoder := yaml.NewDecoder(r)
+	if err := decoder.Decode(&document); err != nil {
+		return nil, fmt.Errorf("failed to decode YAML: %w", err)
+	}
+	root, err := l.resolveIncludes(documentRoot(&document), dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve includes: %w", err)
+	}
+	profile, err := selectProfile(opts.Profile, root)
+	if err != nil {
+		return nil, err
+	}
+	if profile != "" {
+		root = l.applyProfile(root, profile)
+	}
+	if root != documentRoot(&document) {
+		document.Content = []*yaml.Node{root}
+	}
+	if err := interpolate(&document, l.origins); err != nil {
+		return nil, fmt.Errorf("failed to resolve references: %w", err)
+	}
+	var cfg ProjectDefinition
+	if err := l.decode(&document, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to decode YAML: %w", err)
+	}
+	cfg.Codebase.applyPreset()
+	cfg.applyShell()
+	cfg.ActiveProfile = profile
+	cfg.source = &document
+	cfg.origins = l.origins
+	return &cfg, nil
+}
+
+// decodeErrorLine matches the line numbers in the errors of the decoder.
+var decodeErrorLine = regexp.MustCompile(`\bline (\d+):`)
+
+// decode decodes the document into out. Merged documents mix the lines of
+// several files, so the nodes are numbered in order while decoding, and the
+// numbers found in errors are mapped back to the file and line of the node.
+func (l *loader) decode(document *yaml.Node, out any) error {
+	var nodes []*yaml.Node
+	lines := make(map[*yaml.Node]int)
+	var number func(node *yaml.Node)
+	number = func(node *yaml.Node) {
+		if _, seen := lines[node]; seen {
+			return
+		}
+		lines[node] = node.Line
+		nodes = append(nodes, node)
+		node.Line = len(nodes)
+		for _, child := range node.Content {
+			number(child)
+		}
+	}
+	number(document)
+	err := document.Decode(out)
+	for node, line := range lines {
+		node.Line = line
+	}
+	if err == nil {
+		return nil
+	}
+	return errors.New(decodeErrorLine.ReplaceAllStringFunc(err.Error(), func(match string) string {
+		idx, _ := strconv.Atoi(decodeErrorLine.FindStringSubmatch(match)[1])
+		if idx < 1 || idx > len(nodes) {
+			return match
+		}
+		return l.location(nodes[idx-1]) + ":"
+	}))
+}
+
+// resolveIncludes returns the root mapping of the document merged on top
+// of the files it includes. Paths are relative to dir.
+func (l *loader) resolveIncludes(root *yaml.Node, dir string) (*yaml.Node, error) {
+	if root == nil || root.Kind != yaml.MappingNode {
+		return root, nil
+	}
+	includes := mappingValue(root, "include")
+	if includes == nil {
+		return root, nil
+	}
+	if includes.Kind != yaml.SequenceNode {
+		return nil, fmt.Errorf("%s: include must be a list of paths", l.location(includes))
+	}
+	var merged *yaml.Node
+	for _, item := range includes.Content {
+		paths, err := includePaths(dir, item.Value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", l.location(item), err)
+		}
+		for _, path := range paths {
+			included, err := l.includeFile(path)
+			if err != nil {
+				return nil, err
+			}
+			merged = l.merge(merged, included)
+		}
+	}
+	return l.merge(merged, root), nil
+}
+
+// includeFile reads an included file and resolves its own includes.
+func (l *loader) includeFile(path string) (*yaml.Node, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	if idx := slices.Index(l.stack, absPath); idx >= 0 {
+		cycle := append(slices.Clone(l.stack[idx:]), absPath)
+		return nil, fmt.Errorf("include cycle detected: %s", strings.Join(cycle, " -> "))
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read included file: %w", err)
+	}
+	var document yaml.Node
+	if err := yaml.Unmarshal(content, &document); err != nil {
+		return nil, fmt.Errorf("failed to decode included file %s: %w", path, err)
+	}
+	root := documentRoot(&document)
+	if root == nil {
+		return nil, nil
+	}
+	l.record(root, path)
+
+	l.stack = append(l.stack, absPath)
+	defer func() { l.stack = l.stack[:len(l.stack)-1] }()
+	resolved, err := l.resolveIncludes(root, filepath.Dir(path))
+	if err != nil {
+		return nil, err
+	}
+	// Only the includes of the top-level file are kept.
+	stripped := withoutKey(resolved, "include")
+	l.origins[stripped] = path
+	return stripped, nil
+}
+
+// merge merges the overlay node on top of the base node.
+func (l *loader) merge(base *yaml.Node, overlay *yaml.Node) *yaml.Node {
+	if base == nil || overlay == nil {
+		if overlay == nil {
+			return base
+		}
+		return overlay
+	}
+	if base.Kind != yaml.MappingNode || overlay.Kind != yaml.MappingNode {
+		return overlay
+	}
+	merged := *overlay
+	merged.Content = slices.Clone(base.Content)
+	for i := 0; i+1 < len(overlay.Content); i += 2 {
+		key, value := overlay.Content[i], overlay.Content[i+1]
+		idx := mappingIndex(&merged, key.Value)
+		if idx < 0 {
+			merged.Content = append(merged.Content, key, value)
+			continue
+		}
+		merged.Content[idx] = key
+		merged.Content[idx+1] = l.merge(merged.Content[idx+1], value)
+	}
+	if origin, ok := l.origins[overlay]; ok {
+		l.origins[&merged] = origin
+	}
+	return &merged
+}
+
+// record marks every node of the tree as coming from the given file.
+func (l *loader) record(node *yaml.Node, path string) {
+	l.origins[node] = path
+	for _, child := range node.Content {
+		l.record(child, path)
+	}
+}
+
+func (l *loader) location(node *yaml.Node) string {
+	return location(l.origins[node], node.Line)
+}
+
+// location formats a position for error messages. Nodes of the top-level
+// file have no file name.
+func location(file string, line int) string {
+	if file == "" {
+		return fmt.Sprintf("line %d", line)
+	}
+	return fmt.Sprintf("%s:%d", file, line)
+}
+
+// includePaths returns the files matching an include entry, in lexical
+// order for glob patterns.
+func includePaths(dir string, pattern string) ([]string, error) {
+	path := filepath.Join(dir, pattern)
+	if !strings.ContainsAny(pattern, "*?[") {
+		return []string{path}, nil
+	}
+	matches, err := filepath.Glob(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid include pattern '%s': %w", pattern, err)
+	}
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("include pattern '%s' matches no files", pattern)
+	}
+	return matches, nil
+}
+
+func mappingIndex(node *yaml.Node, key string) int {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if idx := mappingIndex(node, key); idx >= 0 {
+		return node.Content[idx+1]
+	}
+	return nil
+}
+
+func withoutKey(node *yaml.Node, key string) *yaml.Node {
+	idx := mappingIndex(node, key)
+	if idx < 0 {
+		return node
+	}
+	stripped := *node
+	stripped.Content = slices.Delete(slices.Clone(node.Content), idx, idx+2)
+	return &stripped
+}
@@ -0,0 +1,174 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+	return dir
+}
+
+func TestLoadFileMergesIncludes(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"shared/go.yaml": `
+vars:
+  output_dir: dist
+codebase:
+  language: go
+  install:
+    steps: [go mod download]
+  build:
+    fail_fast: true
+    env:
+      CGO_ENABLED: "0"
+    steps: [go build ./...]
+`,
+		"shared/tasks/test.yaml": `
+tasks:
+  test:
+    steps: [go test ./...]
+`,
+		"shared/tasks/lint.yaml": `
+tasks:
+  lint:
+    steps: [go vet ./...]
+`,
+		"service/.opsrunner.yaml": `
+include:
+  - ../shared/go.yaml
+  - ../shared/tasks/*.yaml
+name: service
+version: 1.0.0
+codebase:
+  build:
+    env:
+      GOOS: linux
+    steps:
+      - go build -o ${{ vars.output_dir }}/service .
+tasks:
+  lint:
+    steps: [golangci-lint run]
+`,
+	})
+
+	cfg, err := LoadFile(filepath.Join(dir, "service", ".opsrunner.yaml"), nil)
+	require.NoError(t, err)
+	assert.Equal(t, "service", cfg.Name)
+	assert.Equal(t, LanguageGo, cfg.Codebase.Language)
+	assert.Equal(t, []Step{{Run: "go mod download"}}, cfg.Codebase.Install.Steps)
+	// Mappings are merged, lists are replaced.
+	assert.True(t, cfg.Codebase.Build.FailFast)
+	assert.Equal(t, map[string]string{"CGO_ENABLED": "0", "GOOS": "linux"}, cfg.Codebase.Build.Env)
+	assert.Equal(t, []Step{{Run: "go build -o dist/service ."}}, cfg.Codebase.Build.Steps)
+	assert.Equal(t, []string{"lint", "test"}, cfg.TaskNames())
+	assert.Equal(t, []Step{{Run: "golangci-lint run"}}, cfg.Tasks["lint"].Steps)
+	assert.Equal(t, []string{"../shared/go.yaml", "../shared/tasks/*.yaml"}, cfg.Include)
+	require.NoError(t, cfg.Validate())
+}
+
+func TestLoadFileReportsIncludedFile(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"base.yaml": `
+codebase:
+  build:
+    steps: [make]
+    env_mode: sandbox
+`,
+		"main.yaml": `
+include: [base.yaml]
+name: demo
+version: 1.0.0
+codebase:
+  bulid:
+    steps: [make]
+`,
+	})
+
+	cfg, err := LoadFile(filepath.Join(dir, "main.yaml"), nil)
+	require.NoError(t, err)
+	err = cfg.Validate()
+	require.Error(t, err)
+	var diags []string
+	for _, diag := range err.(*ValidationError).Diagnostics {
+		diags = append(diags, diag.String())
+	}
+	assert.Equal(t, []string{
+		"6:3: codebase: unknown field 'bulid' (did you mean 'build'?)",
+		filepath.Join(dir, "base.yaml") + ":5:5: codebase.build.env_mode: unknown env_mode 'sandbox' (expected one of: inherit, clean, allowlist)",
+	}, diags)
+}
+
+func TestLoadFileIncludeErrors(t *testing.T) {
+	tests := map[string]struct {
+		files    map[string]string
+		expected string
+	}{
+		"missing file": {
+			files:    map[string]string{"main.yaml": "include: [missing.yaml]\n"},
+			expected: "failed to read included file",
+		},
+		"no glob matches": {
+			files:    map[string]string{"main.yaml": "include: [shared/*.yaml]\n"},
+			expected: "line 1: include pattern 'shared/*.yaml' matches no files",
+		},
+		"not a list": {
+			files:    map[string]string{"main.yaml": "include: base.yaml\n"},
+			expected: "line 1: include must be a list of paths",
+		},
+		"cycle": {
+			files: map[string]string{
+				"main.yaml": "include: [a.yaml]\n",
+				"a.yaml":    "include: [b.yaml]\n",
+				"b.yaml":    "include: [a.yaml]\n",
+			},
+			expected: "include cycle detected",
+		},
+		"invalid value in included file": {
+			files: map[string]string{
+				"main.yaml":        "include: [shared/base.yaml]\nname: demo\n",
+				"shared/base.yaml": "codebase:\n  build:\n    fail_fast: notabool\n",
+			},
+			expected: filepath.Join("shared", "base.yaml") + ":3: cannot unmarshal !!str `notabool` into bool",
+		},
+		"invalid step in included file": {
+			files: map[string]string{
+				"main.yaml": "include: [base.yaml]\nname: demo\n",
+				"base.yaml": "codebase:\n  build:\n    steps:\n      - dir: src\n",
+			},
+			expected: "base.yaml:4: step is missing the 'run' command",
+		},
+		"invalid value in main file": {
+			files: map[string]string{
+				"main.yaml": "include: [base.yaml]\nname: demo\nshell: [bash]\n",
+				"base.yaml": "version: 1.0.0\n",
+			},
+			expected: "line 3: shell must be a string or a mapping",
+		},
+		"undefined reference in included file": {
+			files: map[string]string{
+				"main.yaml": "include: [a.yaml]\n",
+				"a.yaml":    "name: ${{ vars.missing }}\n",
+			},
+			expected: "a.yaml:1: undefined variable 'missing'",
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			dir := writeFiles(t, tt.files)
+			_, err := LoadFile(filepath.Join(dir, "main.yaml"), nil)
+			assert.ErrorContains(t, err, tt.expected)
+		})
+	}
+}
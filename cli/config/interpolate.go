@@ -0,0 +1,216 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Values of the definition file can reference other values with the
+// ${{ namespace.key }} syntax. References are resolved when the file is
+// loaded, from the following namespaces:
+//
+//	project.KEY    the name, version, description or repo_url of the project
+//	vars.KEY       a variable of the top-level vars block
+//	env.KEY        an environment variable of the calling process
+//
+// References to namespaces only known at run time, such as matrix values,
+// are left for the operation to resolve. A reference prefixed with an
+// extra $, as in $${{ vars.x }}, is kept literally (without the extra $).
+var referencePattern = regexp.MustCompile(`\$?\$\{\{([^}]*)\}\}`)
+
+// runtimeNamespaces are resolved when the steps run rather than on load.
+var runtimeNamespaces = []string{"matrix", "steps"}
+
+// projectFields are the top-level keys exposed in the project namespace.
+var projectFields = []string{"name", "version", "description", "repo_url"}
+
+// interpolator resolves references against the values of a document.
+// Project fields and variables may themselves contain references, which
+// are resolved on first use.
+type interpolator struct {
+	lookupEnv func(string) (string, bool)
+	origins   map[*yaml.Node]string
+	raw       map[string]string
+	resolved  map[string]string
+	resolving []string
+}
+
+func newInterpolator(root *yaml.Node, origins map[*yaml.Node]string) *interpolator {
+	i := &interpolator{
+		lookupEnv: os.LookupEnv,
+		origins:   origins,
+		raw:       make(map[string]string),
+		resolved:  make(map[string]string),
+	}
+	if root == nil || root.Kind != yaml.MappingNode {
+		return i
+	}
+	for idx := 0; idx+1 < len(root.Content); idx += 2 {
+		key, value := root.Content[idx].Value, root.Content[idx+1]
+		if slices.Contains(projectFields, key) && value.Kind == yaml.ScalarNode {
+			i.raw["project."+key] = value.Value
+		}
+		if key == "vars" && value.Kind == yaml.MappingNode {
+			for v := 0; v+1 < len(value.Content); v += 2 {
+				if value.Content[v+1].Kind == yaml.ScalarNode {
+					i.raw["vars."+value.Content[v].Value] = value.Content[v+1].Value
+				}
+			}
+		}
+	}
+	return i
+}
+
+// interpolate replaces the references in every scalar value of the
+// document in place. Profiles are skipped: the selected one is already
+// merged into the document, and the others may reference variables that
+// are only set in the environments they are meant for.
+func interpolate(document *yaml.Node, origins map[*yaml.Node]string) error {
+	root := documentRoot(document)
+	i := newInterpolator(root, origins)
+	if root == nil || root.Kind != yaml.MappingNode {
+		return i.walk(root)
+	}
+	for idx := 0; idx+1 < len(root.Content); idx += 2 {
+		if root.Content[idx].Value == "profiles" {
+			continue
+		}
+		if err := i.walk(root.Content[idx+1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (i *interpolator) walk(node *yaml.Node) error {
+	if node == nil {
+		return nil
+	}
+	switch node.Kind {
+	case yaml.ScalarNode:
+		if !strings.Contains(node.Value, "${{") {
+			return nil
+		}
+		value, err := i.expand(node.Value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", location(i.origins[node], node.Line), err)
+		}
+		node.Value = value
+		if node.Style == 0 {
+			// Let plain scalars resolve to numbers or booleans again.
+			node.Tag = ""
+		}
+	case yaml.MappingNode:
+		for idx := 1; idx < len(node.Content); idx += 2 {
+			if err := i.walk(node.Content[idx]); err != nil {
+				return err
+			}
+		}
+	case yaml.SequenceNode, yaml.DocumentNode:
+		for _, child := range node.Content {
+			if err := i.walk(child); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// expand replaces every reference in the string with its value.
+func (i *interpolator) expand(s string) (string, error) {
+	var err error
+	expanded := referencePattern.ReplaceAllStringFunc(s, func(match string) string {
+		if err != nil {
+			return match
+		}
+		reference := strings.TrimSpace(referencePattern.FindStringSubmatch(match)[1])
+		namespace, _, _ := strings.Cut(reference, ".")
+		if slices.Contains(runtimeNamespaces, namespace) {
+			return match
+		}
+		if strings.HasPrefix(match, "$$") {
+			return match[1:]
+		}
+		var value string
+		value, err = i.resolve(reference)
+		return value
+	})
+	return expanded, err
+}
+
+func (i *interpolator) resolve(reference string) (string, error) {
+	namespace, key, found := strings.Cut(reference, ".")
+	if !found || key == "" {
+		return "", fmt.Errorf("invalid reference '%s' (expected namespace.key)", reference)
+	}
+	switch namespace {
+	case "env":
+		value, ok := i.lookupEnv(key)
+		if !ok {
+			return "", fmt.Errorf("environment variable '%s' is not set", key)
+		}
+		return value, nil
+	case "project":
+		if !slices.Contains(projectFields, key) {
+			return "", fmt.Errorf("unknown project field '%s' (expected one of: %s)", key, strings.Join(projectFields, ", "))
+		}
+	case "vars":
+	default:
+		return "", fmt.Errorf("unknown namespace '%s' in reference '%s' (expected one of: project, vars, env, %s)", namespace, reference, strings.Join(runtimeNamespaces, ", "))
+	}
+
+	if value, ok := i.resolved[reference]; ok {
+		return value, nil
+	}
+	raw, ok := i.raw[reference]
+	if !ok {
+		if namespace == "vars" {
+			return "", fmt.Errorf("undefined variable '%s'", key)
+		}
+		return "", fmt.Errorf("project field '%s' is not set", key)
+	}
+	if slices.Contains(i.resolving, reference) {
+		cycle := append(slices.Clone(i.resolving[slices.Index(i.resolving, reference):]), reference)
+		return "", fmt.Errorf("reference cycle detected: %s", strings.Join(cycle, " -> "))
+	}
+	i.resolving = append(i.resolving, reference)
+	value, err := i.expand(raw)
+	i.resolving = i.resolving[:len(i.resolving)-1]
+	if err != nil {
+		return "", err
+	}
+	i.resolved[reference] = value
+	return value, nil
+}
+
+// expandRuntime replaces the references to the given runtime namespace in
+// the string, leaving any other reference untouched. The lookup receives
+// the part of the reference after the namespace.
+func expandRuntime(s string, namespace string, lookup func(key string) (string, bool)) (string, error) {
+	if !strings.Contains(s, "${{") {
+		return s, nil
+	}
+	var err error
+	expanded := referencePattern.ReplaceAllStringFunc(s, func(match string) string {
+		reference := strings.TrimSpace(referencePattern.FindStringSubmatch(match)[1])
+		refNamespace, key, _ := strings.Cut(reference, ".")
+		if err != nil || refNamespace != namespace {
+			return match
+		}
+		if strings.HasPrefix(match, "$$") {
+			return match[1:]
+		}
+		value, ok := lookup(key)
+		if !ok {
+			err = fmt.Errorf("undefined reference '%s'", reference)
+			return match
+		}
+		return value
+	})
+	return expanded, err
+}
@@ -0,0 +1,90 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadResolvesReferences(t *testing.T) {
+	t.Setenv("OPSRUNNER_TEST_TARGET", "linux/amd64")
+	content := `
+name: demo
+version: 1.4.2
+vars:
+  output_dir: dist/${{ project.name }}
+  binary: ${{ vars.output_dir }}/demo
+  attempts: 3
+codebase:
+  build:
+    steps:
+      - go build -ldflags "-X main.version=${{ project.version }}" -o ${{ vars.binary }}
+      - echo "building for ${{env.OPSRUNNER_TEST_TARGET}}"
+      - echo "literal $${{ vars.binary }}"
+    retry:
+      attempts: ${{ vars.attempts }}
+`
+	cfg, err := Load(strings.NewReader(content))
+	require.NoError(t, err)
+	assert.Equal(t, []Step{
+		{Run: `go build -ldflags "-X main.version=1.4.2" -o dist/demo/demo`},
+		{Run: `echo "building for linux/amd64"`},
+		{Run: `echo "literal ${{ vars.binary }}"`},
+	}, cfg.Codebase.Build.Steps)
+	assert.Equal(t, 3, cfg.Codebase.Build.Retry.Attempts)
+	assert.Equal(t, "dist/demo/demo", cfg.Vars["binary"])
+}
+
+func TestLoadReferenceErrors(t *testing.T) {
+	tests := map[string]struct {
+		value    string
+		expected string
+	}{
+		"undefined variable": {
+			value:    "${{ vars.missing }}",
+			expected: "line 6: undefined variable 'missing'",
+		},
+		"unset environment variable": {
+			value:    "${{ env.OPSRUNNER_TEST_UNSET_VARIABLE }}",
+			expected: "line 6: environment variable 'OPSRUNNER_TEST_UNSET_VARIABLE' is not set",
+		},
+		"unknown project field": {
+			value:    "${{ project.owner }}",
+			expected: "line 6: unknown project field 'owner' (expected one of: name, version, description, repo_url)",
+		},
+		"project field not set": {
+			value:    "${{ project.repo_url }}",
+			expected: "line 6: project field 'repo_url' is not set",
+		},
+		"unknown namespace": {
+			value:    "${{ secrets.token }}",
+			expected: "line 6: unknown namespace 'secrets' in reference 'secrets.token' (expected one of: project, vars, env, matrix, steps)",
+		},
+		"invalid reference": {
+			value:    "${{ version }}",
+			expected: "line 6: invalid reference 'version' (expected namespace.key)",
+		},
+		"cycle": {
+			value:    "${{ vars.a }}",
+			expected: "line 6: reference cycle detected: vars.a -> vars.b -> vars.a",
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			content := `
+name: demo
+version: 1.0.0
+codebase:
+  build:
+    steps: ["echo ` + tt.value + `"]
+vars:
+  a: ${{ vars.b }}
+  b: ${{ vars.a }}
+`
+			_, err := Load(strings.NewReader(content))
+			assert.EqualError(t, err, "failed to resolve references: "+tt.expected)
+		})
+	}
+}
@@ -0,0 +1,235 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"gtithub.com/jgfranco17/opsrunner/cli/logging"
+	"gtithub.com/jgfranco17/opsrunner/cli/outputs"
+)
+
+// Matrix runs an operation once per combination of its values. Every key
+// other than include and exclude is an axis listing the values to combine.
+// Combinations matching all the keys of an exclude entry are dropped, and
+// include entries are added as combinations of their own.
+//
+// The values of a combination are passed to the steps as MATRIX_<KEY>
+// environment variables, and can be referenced as ${{ matrix.KEY }} in
+// step names, commands, directories, conditions and environment variables.
+type Matrix struct {
+	Include []map[string]string `yaml:"include,omitempty" desc:"Additional combinations"`
+	Exclude []map[string]string `yaml:"exclude,omitempty" desc:"Combinations, or parts of them, to leave out"`
+	Axes    map[string][]string `yaml:",inline"`
+}
+
+// Combinations expands the matrix into the list of value sets to run, with
+// the axes combined in alphabetical order of their keys.
+func (m *Matrix) Combinations() []map[string]string {
+	keys := slices.Sorted(maps.Keys(m.Axes))
+	var combinations []map[string]string
+	if len(keys) > 0 {
+		combinations = []map[string]string{{}}
+	}
+	for _, key := range keys {
+		var expanded []map[string]string
+		for _, combination := range combinations {
+			for _, value := range m.Axes[key] {
+				next := maps.Clone(combination)
+				next[key] = value
+				expanded = append(expanded, next)
+			}
+		}
+		combinations = expanded
+	}
+	combinations = slices.DeleteFunc(combinations, func(combination map[string]string) bool {
+		return slices.ContainsFunc(m.Exclude, func(exclude map[string]string) bool {
+			return matches(combination, exclude)
+		})
+	})
+	for _, include := range m.Include {
+		if !slices.ContainsFunc(combinations, func(combination map[string]string) bool {
+			return maps.Equal(combination, include)
+		}) {
+			combinations = append(combinations, maps.Clone(include))
+		}
+	}
+	return combinations
+}
+
+// matches reports whether the combination holds every value of the filter.
+func matches(combination map[string]string, filter map[string]string) bool {
+	for key, value := range filter {
+		if actual, ok := combination[key]; !ok || actual != value {
+			return false
+		}
+	}
+	return true
+}
+
+// combinationLabel formats a combination as key=value pairs in key order.
+func combinationLabel(combination map[string]string) string {
+	keys := slices.Sorted(maps.Keys(combination))
+	pairs := make([]string, len(keys))
+	for idx, key := range keys {
+		pairs[idx] = fmt.Sprintf("%s=%s", key, combination[key])
+	}
+	return strings.Join(pairs, ", ")
+}
+
+var nonAlphanumeric = regexp.MustCompile(`[^A-Za-z0-9]+`)
+
+// matrixEnvName returns the environment variable holding a matrix value.
+func matrixEnvName(key string) string {
+	return "MATRIX_" + strings.ToUpper(nonAlphanumeric.ReplaceAllString(key, "_"))
+}
+
+// runMatrix runs the operation once per combination of its matrix and
+// prints a summary of the combinations. In fail-fast mode, the first
+// failing combination stops the remaining ones.
+func (op *Operation) runMatrix(ctx context.Context, shellExecutor ShellExecutor) error {
+	logger := logging.FromContext(ctx)
+	combinations := op.Matrix.Combinations()
+	if len(combinations) == 0 {
+		logger.Warn("Matrix defines no combinations, nothing to run")
+		return nil
+	}
+
+	results := make([]stepResult, 0, len(combinations))
+	defer func() {
+		printMatrixSummary(results)
+	}()
+	var failed []string
+	for idx, combination := range combinations {
+		label := combinationLabel(combination)
+		if op.FailFast && len(failed) > 0 {
+			results = append(results, stepResult{Label: label, Status: stepSkipped})
+			continue
+		}
+		if ctx.Err() != nil {
+			return fmt.Errorf("operation cancelled before matrix combination '%s': %w", label, context.Cause(ctx))
+		}
+		outputs.PrintColoredMessage("blue", "Matrix %d/%d: %s", idx+1, len(combinations), label)
+		instance, err := op.withMatrix(combination)
+		if err != nil {
+			return fmt.Errorf("matrix combination '%s': %w", label, err)
+		}
+		startTime := time.Now()
+		err = instance.Run(ctx, shellExecutor)
+		record := stepResult{Label: label, Status: stepPassed, Duration: time.Since(startTime)}
+		if err != nil {
+			logger.Errorf("Matrix combination '%s' failed: %v", label, err)
+			record.Status = stepFailed
+			failed = append(failed, label)
+		}
+		results = append(results, record)
+		if ctx.Err() != nil {
+			return err
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("matrix failed for %d of %d combination(s): %s", len(failed), len(combinations), strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// withMatrix returns a copy of the operation for a single combination, with
+// the values injected into its environment and step fields. The hooks run
+// once around all combinations, so the copy has none.
+func (op *Operation) withMatrix(combination map[string]string) (*Operation, error) {
+	instance := *op
+	instance.Matrix = nil
+	instance.Hooks = nil
+	instance.Env = make(map[string]string, len(op.Env)+len(combination))
+	for key, value := range combination {
+		instance.Env[matrixEnvName(key)] = value
+	}
+	lookup := func(key string) (string, bool) {
+		value, ok := combination[key]
+		return value, ok
+	}
+	for key, value := range op.Env {
+		expanded, err := expandRuntime(value, "matrix", lookup)
+		if err != nil {
+			return nil, err
+		}
+		instance.Env[key] = expanded
+	}
+	steps, err := stepsWithValues(op.Steps, "matrix", lookup)
+	if err != nil {
+		return nil, err
+	}
+	instance.Steps = steps
+	return &instance, nil
+}
+
+// stepsWithValues returns copies of the steps with the references of the
+// namespace replaced by the values of the lookup.
+func stepsWithValues(steps []Step, namespace string, lookup func(key string) (string, bool)) ([]Step, error) {
+	if steps == nil {
+		return nil, nil
+	}
+	expanded := make([]Step, len(steps))
+	for idx, step := range steps {
+		var err error
+		for _, field := range []*string{&step.Name, &step.If, &step.Run, &step.Dir} {
+			if *field, err = expandRuntime(*field, namespace, lookup); err != nil {
+				return nil, err
+			}
+		}
+		if step.Env != nil {
+			env := make(map[string]string, len(step.Env))
+			for key, value := range step.Env {
+				if env[key], err = expandRuntime(value, namespace, lookup); err != nil {
+					return nil, err
+				}
+			}
+			step.Env = env
+		}
+		if step.Parallel, err = stepsWithValues(step.Parallel, namespace, lookup); err != nil {
+			return nil, err
+		}
+		expanded[idx] = step
+	}
+	return expanded, nil
+}
+
+func printMatrixSummary(results []stepResult) {
+	if len(results) == 0 {
+		return
+	}
+	outputs.PrintTerminalWideLine("=")
+	fmt.Println("Matrix results:")
+	printStepSummary(results)
+}
+
+// checkMatrix reports the problems of the matrix of the operation, including
+// references to values that none of its combinations define.
+func checkMatrix(op *Operation, report func(message string)) {
+	if len(op.Matrix.Axes) == 0 && len(op.Matrix.Include) == 0 {
+		report("matrix must define at least one value or include")
+		return
+	}
+	for _, key := range slices.Sorted(maps.Keys(op.Matrix.Axes)) {
+		if len(op.Matrix.Axes[key]) == 0 {
+			report(fmt.Sprintf("matrix value '%s' has no entries", key))
+		}
+	}
+	for _, exclude := range op.Matrix.Exclude {
+		for _, key := range slices.Sorted(maps.Keys(exclude)) {
+			if _, ok := op.Matrix.Axes[key]; !ok {
+				report(fmt.Sprintf("exclude refers to unknown matrix value '%s'", key))
+			}
+		}
+	}
+	for _, combination := range op.Matrix.Combinations() {
+		if _, err := op.withMatrix(combination); err != nil {
+			report(fmt.Sprintf("combination '%s': %s", combinationLabel(combination), err))
+			return
+		}
+	}
+}
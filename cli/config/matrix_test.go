@@ -0,0 +1,169 @@
+package config
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestMatrixUnmarshal(t *testing.T) {
+	content := `
+matrix:
+  go: ["1.22", "1.23"]
+  os: [linux, darwin]
+  exclude:
+    - os: darwin
+      go: "1.22"
+  include:
+    - go: "1.24"
+      os: linux
+steps: [go test ./...]
+`
+	var op Operation
+	require.NoError(t, yaml.Unmarshal([]byte(content), &op))
+	require.NotNil(t, op.Matrix)
+	assert.Equal(t, map[string][]string{"go": {"1.22", "1.23"}, "os": {"linux", "darwin"}}, op.Matrix.Axes)
+	assert.Equal(t, []map[string]string{{"os": "darwin", "go": "1.22"}}, op.Matrix.Exclude)
+	assert.Equal(t, []map[string]string{{"go": "1.24", "os": "linux"}}, op.Matrix.Include)
+}
+
+func TestMatrixCombinations(t *testing.T) {
+	matrix := Matrix{
+		Axes: map[string][]string{"os": {"linux", "darwin"}, "go": {"1.22", "1.23"}},
+		Exclude: []map[string]string{
+			{"os": "darwin", "go": "1.22"},
+		},
+		Include: []map[string]string{
+			{"os": "linux", "go": "1.23"},
+			{"os": "linux", "go": "1.24"},
+		},
+	}
+	assert.Equal(t, []map[string]string{
+		{"go": "1.22", "os": "linux"},
+		{"go": "1.23", "os": "linux"},
+		{"go": "1.23", "os": "darwin"},
+		{"go": "1.24", "os": "linux"},
+	}, matrix.Combinations())
+}
+
+func TestMatrixCombinationsExcludePartial(t *testing.T) {
+	matrix := Matrix{
+		Axes:    map[string][]string{"os": {"linux", "darwin"}, "go": {"1.22", "1.23"}},
+		Exclude: []map[string]string{{"os": "darwin"}},
+	}
+	assert.Equal(t, []map[string]string{
+		{"go": "1.22", "os": "linux"},
+		{"go": "1.23", "os": "linux"},
+	}, matrix.Combinations())
+}
+
+func TestMatrixRunInjectsValues(t *testing.T) {
+	exec := &fakeExecutor{}
+	op := Operation{
+		Matrix: &Matrix{Axes: map[string][]string{"go-version": {"1.22", "1.23"}}},
+		Env:    map[string]string{"GOTOOLCHAIN": "go${{ matrix.go-version }}"},
+		Steps: []Step{
+			{Run: "go test ./... # ${{ matrix.go-version }}"},
+			{Run: "echo $${{ matrix.go-version }}"},
+		},
+	}
+	require.NoError(t, op.Run(context.Background(), exec))
+	assert.Equal(t, []string{
+		"go test ./... # 1.22",
+		"echo ${{ matrix.go-version }}",
+		"go test ./... # 1.23",
+		"echo ${{ matrix.go-version }}",
+	}, exec.commands)
+	assert.Contains(t, exec.received[0].Env, "MATRIX_GO_VERSION=1.22")
+	assert.Contains(t, exec.received[0].Env, "GOTOOLCHAIN=go1.22")
+	assert.Contains(t, exec.received[2].Env, "MATRIX_GO_VERSION=1.23")
+}
+
+func TestMatrixRunReportsFailedCombinations(t *testing.T) {
+	exec := &fakeExecutor{exitCodes: map[string]int{"test linux": 1}}
+	op := Operation{
+		Matrix: &Matrix{Axes: map[string][]string{"os": {"linux", "darwin", "windows"}}},
+		Steps:  []Step{{Run: "test ${{ matrix.os }}"}},
+	}
+	err := op.Run(context.Background(), exec)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "matrix failed for 1 of 3 combination(s): os=linux")
+	assert.Equal(t, []string{"test linux", "test darwin", "test windows"}, exec.commands)
+}
+
+func TestMatrixRunFailFastSkipsRemaining(t *testing.T) {
+	exec := &fakeExecutor{exitCodes: map[string]int{"test linux": 1}}
+	op := Operation{
+		FailFast: true,
+		Matrix:   &Matrix{Axes: map[string][]string{"os": {"linux", "darwin"}}},
+		Steps:    []Step{{Run: "test ${{ matrix.os }}"}},
+	}
+	require.Error(t, op.Run(context.Background(), exec))
+	assert.Equal(t, []string{"test linux"}, exec.commands)
+}
+
+func TestMatrixRunHooksOnce(t *testing.T) {
+	exec := &fakeExecutor{exitCodes: map[string]int{"test darwin": 1}}
+	op := Operation{
+		Matrix: &Matrix{Axes: map[string][]string{"os": {"linux", "darwin"}}},
+		Steps:  []Step{{Run: "test ${{ matrix.os }}"}},
+		Hooks: &Hooks{
+			Before:    &Operation{Steps: []Step{{Run: "before"}}},
+			After:     &Operation{Steps: []Step{{Run: "after"}}},
+			OnFailure: &Operation{Steps: []Step{{Run: "on_failure"}}},
+			Always:    &Operation{Steps: []Step{{Run: "always"}}},
+		},
+	}
+	require.Error(t, op.Run(context.Background(), exec))
+	assert.Equal(t, []string{"before", "test linux", "test darwin", "on_failure", "always"}, exec.commands)
+
+	exec = &fakeExecutor{}
+	require.NoError(t, op.Run(context.Background(), exec))
+	assert.Equal(t, []string{"before", "test linux", "test darwin", "after", "always"}, exec.commands)
+}
+
+func TestMatrixLoadKeepsReferences(t *testing.T) {
+	cfg, err := Load(strings.NewReader(`---
+name: demo
+version: 1.0.0
+vars:
+  pkg: ./...
+codebase:
+  build:
+    matrix:
+      go: ["1.22"]
+    steps:
+      - go${{ matrix.go }} test ${{ vars.pkg }}
+`))
+	require.NoError(t, err)
+	assert.Equal(t, "go${{ matrix.go }} test ./...", cfg.Codebase.Build.Steps[0].Run)
+	assert.NoError(t, cfg.Validate())
+}
+
+func TestValidateFail_Matrix(t *testing.T) {
+	diags := validationDiagnostics(t, `---
+name: demo
+version: 1.0.0
+codebase:
+  build:
+    matrix:
+      os: [linux]
+      exclude:
+        - arch: arm64
+    steps:
+      - make ${{ matrix.target }}
+tasks:
+  lint:
+    matrix: {}
+    steps: [make lint]
+`)
+	assert.Equal(t, []string{
+		"6:5: codebase.build.matrix: exclude refers to unknown matrix value 'arch'",
+		"6:5: codebase.build.matrix: combination 'os=linux': undefined reference 'matrix.target'",
+		"14:5: tasks.lint.matrix: matrix must define at least one value or include",
+	}, diags)
+}
@@ -1,10 +1,15 @@
 package config
 
 import (
+	"cmp"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"sort"
+	"time"
 
 	"gtithub.com/jgfranco17/opsrunner/cli/executor"
 	"gtithub.com/jgfranco17/opsrunner/cli/logging"
@@ -14,77 +19,403 @@ import (
 )
 
 type ShellExecutor interface {
-	Exec(ctx context.Context, command string) (executor.Result, error)
-	AddEnv(env []string)
+	Exec(ctx context.Context, command executor.Command) (executor.Result, error)
+}
+
+// SessionStarter is implemented by executors that can run the steps of an
+// operation in a single shell session.
+type SessionStarter interface {
+	NewSession() *executor.Session
 }
 
 type ProjectDefinition struct {
-	Name        string   `yaml:"name"`
-	Description string   `yaml:"description,omitempty"`
-	Version     string   `yaml:"version"`
-	RepoUrl     string   `yaml:"repo_url"`
-	Codebase    Codebase `yaml:"codebase"`
+	Name        string             `yaml:"name" required:"true" desc:"Name of the project"`
+	Description string             `yaml:"description,omitempty" desc:"Short summary of the project"`
+	Version     string             `yaml:"version" required:"true" desc:"Semantic version of the project, e.g. 1.2.3"`
+	RepoUrl     string             `yaml:"repo_url" desc:"URL of the project repository"`
+	Include     []string           `yaml:"include,omitempty" desc:"Files merged into this one, as paths or glob patterns relative to it"`
+	Vars        map[string]string  `yaml:"vars,omitempty" desc:"Variables that values can reference as ${{ vars.NAME }}"`
+	Codebase    Codebase           `yaml:"codebase" desc:"Install and build operations of the codebase"`
+	Tasks       map[string]Task    `yaml:"tasks,omitempty" desc:"Named tasks that can be invoked with 'opsrunner run'"`
+	Hooks       *Hooks             `yaml:"hooks,omitempty" desc:"Operations run around every build and task invocation"`
+	Shell       *Shell             `yaml:"shell,omitempty" desc:"Program running the steps, bash by default"`
+	Profiles    map[string]Profile `yaml:"profiles,omitempty" desc:"Named overlays, selected with --profile or OPSRUNNER_PROFILE"`
+
+	// ActiveProfile is the name of the profile applied when loading.
+	ActiveProfile string `yaml:"-"`
+
+	// source is the parsed document, kept for positional diagnostics, and
+	// origins maps the nodes merged in from included files to their file.
+	source  *yaml.Node
+	origins map[*yaml.Node]string
 }
 
-// Load reads a YAML configuration from the provided reader and unmarshals
-// it into a struct instance.
+// Load reads a YAML configuration from the provided reader, resolves its
+// includes relative to the working directory and the ${{ ... }}
+// references in its values, and unmarshals it into a struct instance.
 func Load(r io.Reader) (*ProjectDefinition, error) {
-	var cfg ProjectDefinition
-	decoder := yaml.NewDecoder(r)
-	if err := decoder.Decode(&cfg); err != nil {
-		return nil, fmt.Errorf("failed to decode YAML: %w", err)
+	return newLoader().load(r, ".", nil)
+}
+
+// LoadFile reads the YAML configuration at the given path, resolving its
+// includes relative to the file and applying the selected profile.
+func LoadFile(path string, opts *LoadOptions) (*ProjectDefinition, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file %s: %w", path, err)
+	}
+	defer file.Close()
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	l := newLoader()
+	l.stack = []string{absPath}
+	return l.load(file, filepath.Dir(path), opts)
+}
+
+// Language identifies the programming language of a codebase.
+type Language string
+
+const (
+	LanguageGo     Language = "go"
+	LanguagePython Language = "python"
+	LanguageNode   Language = "node"
+	LanguageRust   Language = "rust"
+	LanguageJava   Language = "java"
+)
+
+// SupportedLanguages lists every language accepted in a codebase definition.
+var SupportedLanguages = []Language{LanguageGo, LanguagePython, LanguageNode, LanguageRust, LanguageJava}
+
+func (l Language) enumValues() []string {
+	values := make([]string, len(SupportedLanguages))
+	for idx, language := range SupportedLanguages {
+		values[idx] = string(language)
 	}
-	return &cfg, nil
+	return values
 }
 
+// Codebase describes how to install the dependencies of the project and
+// build it. Operations without steps fall back to the preset of the
+// language when loaded from a file.
 type Codebase struct {
-	Language     string    `yaml:"language"`
-	Dependencies string    `yaml:"dependencies,omitempty"`
-	Install      Operation `yaml:"install,omitempty"`
-	Build        Operation `yaml:"build,omitempty"`
+	Language     Language  `yaml:"language" desc:"Programming language of the codebase"`
+	Dependencies string    `yaml:"dependencies,omitempty" desc:"Comma-separated dependency files or glob patterns, e.g. go.mod,go.sum; the install is skipped while they are unchanged"`
+	Install      Operation `yaml:"install,omitempty" desc:"Operation that installs the codebase dependencies"`
+	Build        Operation `yaml:"build,omitempty" desc:"Operation that builds the codebase"`
+}
+
+// Task is a named operation that can be invoked on demand with the
+// run command, e.g. test, lint, release or deploy flows. Tasks listed in
+// DependsOn (including the codebase "install" and "build" operations)
+// are run first. Tasks declaring Inputs are skipped when neither their
+// inputs, outputs, steps nor environment changed since their last
+// successful run.
+type Task struct {
+	Description string   `yaml:"description,omitempty" desc:"Short summary shown when listing tasks"`
+	Category    string   `yaml:"category,omitempty" desc:"Free-form group of the task, e.g. test or release"`
+	DependsOn   []string `yaml:"depends_on,omitempty" desc:"Tasks, or the install and build operations, to run first"`
+	Inputs      []string `yaml:"inputs,omitempty" desc:"Files or glob patterns read by the task; the task is skipped while they and its outputs are unchanged"`
+	Outputs     []string `yaml:"outputs,omitempty" desc:"Files or glob patterns produced by the task"`
+	Operation   `yaml:",inline"`
 }
 
 type Operation struct {
-	FailFast bool              `yaml:"fail_fast,omitempty"`
-	Env      map[string]string `yaml:"env,omitempty"`
-	Steps    []string          `yaml:"steps"`
+	If           string            `yaml:"if,omitempty" desc:"Condition that must hold for the operation to run, e.g. env.CI == \"true\""`
+	FailFast     bool              `yaml:"fail_fast,omitempty" desc:"Stop at the first failing step"`
+	Session      bool              `yaml:"session,omitempty" desc:"Run the steps in a single shell, keeping directory changes, functions and exports between them"`
+	Shell        *Shell            `yaml:"shell,omitempty" desc:"Program running the steps, overriding the one of the project"`
+	Env          map[string]string `yaml:"env,omitempty" desc:"Environment variables set for every step"`
+	EnvMode      EnvMode           `yaml:"env_mode,omitempty" desc:"Variables passed on from the calling environment, inherit by default"`
+	EnvAllowlist []string          `yaml:"env_allowlist,omitempty" desc:"Variables passed on from the calling environment in allowlist mode"`
+	Requires     []string          `yaml:"requires,omitempty" desc:"Executables that must be on PATH for the operation to run"`
+	Timeout      time.Duration     `yaml:"timeout,omitempty" desc:"Maximum run time of the whole operation, e.g. 10m"`
+	Retry        *RetryPolicy      `yaml:"retry,omitempty" desc:"Default retry policy of the steps"`
+	Matrix       *Matrix           `yaml:"matrix,omitempty" desc:"Values to run the operation with, once per combination"`
+	Steps        []Step            `yaml:"steps" desc:"Commands to run, in order"`
+	Hooks        *Hooks            `yaml:"hooks,omitempty" desc:"Operations run around the steps"`
+}
+
+// definition serializes the whole operation, including its nested steps,
+// environment settings and hooks, for use in cache keys.
+func (op *Operation) definition() (string, error) {
+	content, err := yaml.Marshal(op)
+	if err != nil {
+		return "", fmt.Errorf("failed to serialize operation: %w", err)
+	}
+	return string(content), nil
 }
 
 // Run executes the defined steps in the Operation using the provided envs.
-func (op *Operation) Run(ctx context.Context, executor ShellExecutor) error {
+func (op *Operation) Run(ctx context.Context, shellExecutor ShellExecutor) error {
 	logger := logging.FromContext(ctx)
 
-	env := os.Environ()
+	if run, err := op.enabled(); err != nil {
+		return err
+	} else if !run {
+		logger.Infof("Skipping operation, condition not met: %s", op.If)
+		return nil
+	}
+	return op.Hooks.around(ctx, shellExecutor, func(ctx context.Context) error {
+		if op.Matrix != nil {
+			return op.runMatrix(ctx, shellExecutor)
+		}
+		return op.runSteps(ctx, shellExecutor)
+	})
+}
+
+// enabled reports whether the condition of the operation holds.
+func (op *Operation) enabled() (bool, error) {
+	if usesStatus(op.If) {
+		return false, errOperationStatus
+	}
+	return evalCondition(op.If, newExprContext(false))
+}
+
+func (op *Operation) runSteps(ctx context.Context, shellExecutor ShellExecutor) error {
+	logger := logging.FromContext(ctx)
+
+	if err := checkRequires(op.Requires); err != nil {
+		return err
+	}
+	if op.EnvMode != "" {
+		logger.Debugf("Using environment mode '%s'", op.EnvMode)
+	}
+	files, err := newEnvFiles()
+	if err != nil {
+		return err
+	}
+	defer files.Close()
+	env := mergeEnv(op.EnvMode.environ(op.EnvAllowlist), envList(op.Env), files.environ())
 	if len(op.Env) > 0 {
 		envsAdded := []string{}
-		for k, v := range op.Env {
-			env = append(env, fmt.Sprintf("%s=%s", k, v))
+		for k := range op.Env {
 			envsAdded = append(envsAdded, k)
 		}
-		logger.Infof("Loading additional %d additional environment variable(s): %v", len(op.Env), envsAdded)
+		sort.Strings(envsAdded)
+		logger.Infof("Loading %d additional environment variable(s): %v", len(op.Env), envsAdded)
+	}
+
+	ctx, cancel := withTimeout(ctx, op.Timeout, timeoutScopeOperation)
+	defer cancel()
+
+	if op.Session {
+		starter, ok := shellExecutor.(SessionStarter)
+		if !ok {
+			return fmt.Errorf("the executor does not support shell sessions")
+		}
+		session := starter.NewSession()
+		defer session.Close()
+		shellExecutor = session
 	}
-	executor.AddEnv(env)
 
-	var failedSteps []string
+	state := &runState{outputs: stepOutputs{}}
+	names := stepNames(op.Steps)
+	defer func() {
+		outputs.PrintTerminalWideLine("=")
+		printStepSummary(state.results)
+	}()
+
 	for idx, step := range op.Steps {
-		fmt.Printf("[%d] %s\n", idx+1, step)
-		result, err := executor.Exec(ctx, step)
-		if err != nil || result.ExitCode != 0 {
-			if op.FailFast {
-				return fmt.Errorf("error while running '%s' (exit code %d): %w", step, result.ExitCode, err)
+		if ctx.Err() != nil {
+			return fmt.Errorf("operation cancelled before step '%s': %w", step.Label(), context.Cause(ctx))
+		}
+		step, err := state.outputs.expand(step, names)
+		if err != nil {
+			return fmt.Errorf("step '%s': %w", step.Label(), err)
+		}
+		run, err := step.shouldRun(state.abortErr != nil, state.failed())
+		if err != nil {
+			return fmt.Errorf("step '%s': %w", step.Label(), err)
+		}
+		if !run {
+			logger.Infof("Skipping step '%s'", step.Label())
+			state.results = append(state.results, stepResult{Label: step.Label(), Status: stepSkipped})
+			continue
+		}
+		fmt.Printf("[%d] %s\n", idx+1, step.Label())
+		var runs []stepRun
+		var groupErr error
+		if len(step.Parallel) > 0 {
+			runs, groupErr = op.runGroup(ctx, shellExecutor, step, files.apply(env), idx+1, state.failed())
+		} else {
+			runs = []stepRun{op.execStep(ctx, shellExecutor, step, files.apply(env))}
+		}
+		for _, run := range runs {
+			if err := state.record(ctx, op, run); err != nil {
+				return err
 			}
-			failedSteps = append(failedSteps, step)
 		}
-		if result.Stdout != "" {
-			_, _ = fmt.Fprintf(os.Stdout, "%s\n", result.Stdout)
+		if err := files.update(step.Dir); err != nil {
+			return fmt.Errorf("step '%s': %w", step.Label(), err)
+		}
+		if groupErr != nil {
+			return groupErr
 		}
-		if result.Stderr != "" {
-			_, _ = fmt.Fprintf(os.Stderr, "%s\n", result.Stderr)
+	}
+	return state.err()
+}
+
+// stepRun is the outcome of running a single step.
+type stepRun struct {
+	step     Step
+	result   executor.Result
+	attempts int
+	err      error
+	duration time.Duration
+	outputs  map[string]string
+	// skipped is set when the condition of the step did not hold, and
+	// halted when the step was stopped, or never started, because a step
+	// running alongside it failed.
+	skipped bool
+	halted  bool
+}
+
+func (op *Operation) execStep(ctx context.Context, shellExecutor ShellExecutor, step Step, env []string) stepRun {
+	startTime := time.Now()
+	outputFile, err := newOutputFile()
+	if err != nil {
+		return stepRun{step: step, err: err}
+	}
+	env = mergeEnv(env, []string{OutputEnvVar + "=" + outputFile})
+	result, attempts, err := op.runStepWithRetry(ctx, shellExecutor, step, env)
+	outputs, outputErr := readOutputs(outputFile)
+	if outputErr != nil {
+		logging.FromContext(ctx).Errorf("Step '%s' wrote invalid outputs: %v", step.Label(), outputErr)
+		if err == nil {
+			err = outputErr
 		}
 	}
-	outputs.PrintTerminalWideLine("=")
-	if len(failedSteps) > 0 {
-		return fmt.Errorf("failed to run steps: %v", failedSteps)
+	return stepRun{
+		step:     step,
+		result:   result,
+		attempts: attempts,
+		err:      err,
+		duration: time.Since(startTime),
+		outputs:  outputs,
+	}
+}
+
+// runState tracks the outcome of the steps of an operation.
+type runState struct {
+	results     []stepResult
+	failedSteps []string
+	timeouts    []error
+	outputs     stepOutputs
+	// abortErr is set when a step failed in fail-fast mode.
+	abortErr error
+}
+
+func (s *runState) failed() bool {
+	return s.abortErr != nil || len(s.failedSteps) > 0
+}
+
+// record adds the outcome of a step to the state. It returns an error when
+// the operation must stop right away, i.e. when it was cancelled or timed
+// out as a whole.
+func (s *runState) record(ctx context.Context, op *Operation, run stepRun) error {
+	logger := logging.FromContext(ctx)
+	label := run.step.Label()
+	record := stepResult{
+		Label:    label,
+		Status:   stepPassed,
+		ExitCode: run.result.ExitCode,
+		Attempts: run.attempts,
+		Duration: run.duration,
+	}
+	switch {
+	case run.skipped:
+		s.results = append(s.results, stepResult{Label: label, Status: stepSkipped})
+		return nil
+	case run.halted:
+		record.Status = stepCancelled
+		s.results = append(s.results, record)
+		return nil
 	}
+	if run.step.Name != "" && len(run.outputs) > 0 {
+		s.outputs[run.step.Name] = run.outputs
+	}
+
+	var timeoutErr *TimeoutError
+	var cancelled *executor.CancelledError
+	var ended *executor.SessionEndedError
+	if errors.As(run.err, &ended) {
+		logger.Errorf("Step '%s' could not run: %v", label, ended)
+	} else if errors.As(run.err, &timeoutErr) {
+		logger.Error(timeoutErr.Error())
+		record.Status = stepTimedOut
+		if timeoutErr.Scope != timeoutScopeStep {
+			s.results = append(s.results, record)
+			return run.err
+		}
+		s.timeouts = append(s.timeouts, run.err)
+	} else if errors.As(run.err, &cancelled) && ctx.Err() != nil {
+		logger.Warnf("Step '%s' was cancelled", label)
+		record.Status = stepCancelled
+		s.results = append(s.results, record)
+		return fmt.Errorf("step '%s' cancelled: %w", label, run.err)
+	}
+	if run.err != nil || run.result.ExitCode != 0 {
+		if record.Status == stepPassed {
+			record.Status = stepFailed
+		}
+		if run.step.ContinueOnError {
+			logger.Warnf("Step '%s' failed (exit code %d), continuing", label, run.result.ExitCode)
+			record.Status = stepIgnored
+		} else if op.FailFast && s.abortErr == nil {
+			// Keep going only for steps that handle the failure.
+			s.abortErr = fmt.Errorf("error while running '%s' (exit code %d, %d attempt(s))", label, run.result.ExitCode, run.attempts)
+			if run.err != nil {
+				s.abortErr = fmt.Errorf("%w: %w", s.abortErr, run.err)
+			}
+		} else {
+			s.failedSteps = append(s.failedSteps, label)
+		}
+	}
+	s.results = append(s.results, record)
 	return nil
 }
+
+func (s *runState) err() error {
+	if s.abortErr != nil {
+		return s.abortErr
+	}
+	if len(s.failedSteps) > 0 {
+		if len(s.timeouts) > 0 {
+			return fmt.Errorf("failed to run steps: %v: %w", s.failedSteps, errors.Join(s.timeouts...))
+		}
+		return fmt.Errorf("failed to run steps: %v", s.failedSteps)
+	}
+	return nil
+}
+
+func (op *Operation) runStep(ctx context.Context, shellExecutor ShellExecutor, step Step, env []string) (executor.Result, error) {
+	ctx, cancel := withTimeout(ctx, step.Timeout, timeoutScopeStep)
+	defer cancel()
+
+	stdout, stderr := outputs.FromContext(ctx).Writers(step.Label())
+	defer func() {
+		_ = stdout.Flush()
+		_ = stderr.Flush()
+	}()
+	command := executor.Command{
+		Run:    step.Run,
+		Dir:    step.Dir,
+		Env:    mergeEnv(env, envList(step.Env)),
+		Stdout: stdout,
+		Stderr: stderr,
+	}
+	if shell := cmp.Or(step.Shell, op.Shell); shell != nil {
+		command.Shell, command.Strict = shell.Command, shell.Strict
+	}
+	startTime := time.Now()
+	result, err := shellExecutor.Exec(ctx, command)
+	var cancelled *executor.CancelledError
+	if errors.As(err, &cancelled) {
+		if timeoutErr, ok := asTimeout(ctx, step.Label(), time.Since(startTime)); ok {
+			return result, timeoutErr
+		}
+	}
+	return result, err
+}
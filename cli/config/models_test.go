@@ -1,10 +1,15 @@
 package config
 
 import (
+	"context"
+	"errors"
+	"os"
 	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+
+	"gtithub.com/jgfranco17/opsrunner/cli/executor"
 )
 
 func TestLoadConfigOk(t *testing.T) {
@@ -17,13 +22,18 @@ tasks:
     env:
       IS_TEST: "true"
     steps:
-      - cmd: ls
-        args: -la
+      - ls -la
 `
 	reader := strings.NewReader(simpleConfig)
 	config, err := Load(reader)
 	assert.NoError(t, err, "Unexpected error while loading config from file")
 	assert.Equal(t, "www.example.com", config.RepoUrl)
+	assert.Contains(t, config.Tasks, "test")
+	task := config.Tasks["test"]
+	assert.Equal(t, "Hello World", task.Description)
+	assert.Equal(t, "test", task.Category)
+	assert.Equal(t, map[string]string{"IS_TEST": "true"}, task.Env)
+	assert.Equal(t, []Step{{Run: "ls -la"}}, task.Steps)
 }
 
 func TestLoadConfigFail_InvalidYamlSchema(t *testing.T) {
@@ -32,3 +42,26 @@ func TestLoadConfigFail_InvalidYamlSchema(t *testing.T) {
 	assert.ErrorContains(t, err, "cannot unmarshal")
 	assert.Empty(t, config)
 }
+
+type cancellingExecutor struct {
+	cancel   context.CancelCauseFunc
+	commands []string
+}
+
+func (c *cancellingExecutor) Exec(ctx context.Context, command executor.Command) (executor.Result, error) {
+	c.commands = append(c.commands, command.Run)
+	c.cancel(&executor.InterruptError{Signal: os.Interrupt})
+	return executor.Result{ExitCode: -1}, &executor.CancelledError{Cause: context.Cause(ctx)}
+}
+
+func TestOperationRunReportsCancellation(t *testing.T) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	exec := &cancellingExecutor{cancel: cancel}
+	op := Operation{Steps: []Step{{Run: "sleep 60"}, {Run: "echo next"}}}
+
+	err := op.Run(ctx, exec)
+	assert.ErrorContains(t, err, "step 'sleep 60' cancelled")
+	var interrupt *executor.InterruptError
+	assert.True(t, errors.As(err, &interrupt))
+	assert.Equal(t, []string{"sleep 60"}, exec.commands)
+}
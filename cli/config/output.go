@@ -0,0 +1,128 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+// OutputEnvVar names the environment variable holding the path of the file
+// a step can write its outputs to. Later steps of the same operation refer
+// to them as ${{ steps.<name>.outputs.<key> }}.
+const OutputEnvVar = "OPSRUNNER_OUTPUT"
+
+// stepOutputs holds the outputs of the steps of an operation by step name.
+type stepOutputs map[string]map[string]string
+
+// expand returns a copy of the step with its references to the outputs of
+// the named steps replaced. Outputs that were not written, for example by a
+// skipped step, are empty.
+func (o stepOutputs) expand(step Step, names []string) (Step, error) {
+	expanded, err := stepsWithValues([]Step{step}, "steps", func(key string) (string, bool) {
+		name, output, ok := outputReference(key)
+		if !ok || !slices.Contains(names, name) {
+			return "", false
+		}
+		return o[name][output], true
+	})
+	if err != nil {
+		return step, err
+	}
+	return expanded[0], nil
+}
+
+// outputReference splits a reference to a step output, without its steps
+// namespace, into the name of the step and the output key.
+func outputReference(reference string) (string, string, bool) {
+	name, key, found := strings.Cut(reference, ".outputs.")
+	if !found || name == "" || key == "" {
+		return "", "", false
+	}
+	return name, key, true
+}
+
+// stepReferences returns the references to step outputs in the string,
+// without their steps namespace.
+func stepReferences(s string) []string {
+	var references []string
+	for _, match := range referencePattern.FindAllStringSubmatch(s, -1) {
+		if strings.HasPrefix(match[0], "$$") {
+			continue
+		}
+		namespace, key, _ := strings.Cut(strings.TrimSpace(match[1]), ".")
+		if namespace == "steps" {
+			references = append(references, key)
+		}
+	}
+	return references
+}
+
+// stepNames returns the names of the steps, including the ones of parallel
+// groups, in the order they are defined.
+func stepNames(steps []Step) []string {
+	var names []string
+	for _, step := range steps {
+		if step.Name != "" {
+			names = append(names, step.Name)
+		}
+		names = append(names, stepNames(step.Parallel)...)
+	}
+	return names
+}
+
+// newOutputFile creates an empty file for a step to write its outputs to.
+func newOutputFile() (string, error) {
+	file, err := os.CreateTemp("", "opsrunner-output-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create output file: %w", err)
+	}
+	return file.Name(), file.Close()
+}
+
+// readOutputs parses and removes the output file of a step.
+func readOutputs(path string) (map[string]string, error) {
+	defer os.Remove(path)
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read output file: %w", err)
+	}
+	return parseOutputs(string(content))
+}
+
+// parseOutputs reads key=value lines. A value spanning several lines is
+// written as key<<DELIMITER, followed by the lines of the value and a line
+// holding only the delimiter. Empty lines are ignored, and a key written
+// again overrides its earlier value.
+func parseOutputs(content string) (map[string]string, error) {
+	outputs := map[string]string{}
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+	for idx := 0; idx < len(lines); idx++ {
+		line := lines[idx]
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if key, delimiter, ok := strings.Cut(line, "<<"); ok && !strings.Contains(key, "=") {
+			var value []string
+			closed := false
+			for idx++; idx < len(lines); idx++ {
+				if lines[idx] == delimiter {
+					closed = true
+					break
+				}
+				value = append(value, lines[idx])
+			}
+			if key == "" || delimiter == "" || !closed {
+				return nil, fmt.Errorf("invalid output '%s': expected a value ending with '%s'", line, delimiter)
+			}
+			outputs[key] = strings.Join(value, "\n")
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid output '%s': expected key=value", line)
+		}
+		outputs[key] = value
+	}
+	return outputs, nil
+}
@@ -0,0 +1,86 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gtithub.com/jgfranco17/opsrunner/cli/executor"
+)
+
+func TestParseOutputs(t *testing.T) {
+	outputs, err := parseOutputs("version=1.2.3\n\nflags=-X main.a=b\nnotes<<EOF\nfirst line\nsecond line\nEOF\nversion=1.2.4\n")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"version": "1.2.4",
+		"flags":   "-X main.a=b",
+		"notes":   "first line\nsecond line",
+	}, outputs)
+}
+
+func TestParseOutputsInvalid(t *testing.T) {
+	_, err := parseOutputs("version\n")
+	assert.EqualError(t, err, "invalid output 'version': expected key=value")
+
+	_, err = parseOutputs("notes<<EOF\nunterminated\n")
+	assert.EqualError(t, err, "invalid output 'notes<<EOF': expected a value ending with 'EOF'")
+}
+
+func TestOutputReference(t *testing.T) {
+	name, key, ok := outputReference("build image.outputs.digest")
+	assert.True(t, ok)
+	assert.Equal(t, "build image", name)
+	assert.Equal(t, "digest", key)
+
+	_, _, ok = outputReference("build.digest")
+	assert.False(t, ok)
+}
+
+func TestOperationRunPassesStepOutputs(t *testing.T) {
+	result := filepath.Join(t.TempDir(), "result")
+	op := Operation{
+		Steps: []Step{
+			{Name: "version", Run: `echo "tag=v1.2.3" >> "$OPSRUNNER_OUTPUT"`},
+			{Name: "skipped", If: "false", Run: `echo "tag=never" >> "$OPSRUNNER_OUTPUT"`},
+			{
+				Run: `echo "${{ steps.version.outputs.tag }}/${{ steps.skipped.outputs.tag }}/"'$${{ steps.version.outputs.tag }}' > ` + result,
+			},
+		},
+	}
+	require.NoError(t, op.Run(context.Background(), &executor.DefaultExecutor{}))
+
+	content, err := os.ReadFile(result)
+	require.NoError(t, err)
+	assert.Equal(t, "v1.2.3//${{ steps.version.outputs.tag }}\n", string(content))
+}
+
+func TestOperationRunInvalidOutputFailsStep(t *testing.T) {
+	op := Operation{
+		Steps: []Step{{Name: "version", Run: `echo "tag" >> "$OPSRUNNER_OUTPUT"`}},
+	}
+	err := op.Run(context.Background(), &executor.DefaultExecutor{})
+	assert.ErrorContains(t, err, "failed to run steps: [version]")
+}
+
+func TestValidateFail_StepOutputReferences(t *testing.T) {
+	diags := validationDiagnostics(t, `---
+name: demo
+version: 1.0.0
+codebase:
+  build:
+    steps:
+      - run: echo ${{ steps.tag.outputs.value }}
+      - name: tag
+        run: echo "value=1" >> "$OPSRUNNER_OUTPUT"
+      - run: echo ${{ steps.tag.value }}
+      - run: echo ${{ steps.tag.outputs.value }}
+`)
+	assert.Equal(t, []string{
+		"6:5: codebase.build.steps: step 1 refers to the outputs of 'tag', which is not an earlier step",
+		"6:5: codebase.build.steps: step 3: invalid reference 'steps.tag.value' (expected steps.<name>.outputs.<key>)",
+	}, diags)
+}
@@ -0,0 +1,98 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"gtithub.com/jgfranco17/opsrunner/cli/executor"
+	"gtithub.com/jgfranco17/opsrunner/cli/logging"
+	"gtithub.com/jgfranco17/opsrunner/cli/outputs"
+)
+
+// errSiblingFailed cancels the steps of a parallel group once one of them
+// failed in fail-fast mode.
+var errSiblingFailed = errors.New("a parallel step failed")
+
+// runGroup runs the steps of a parallel group concurrently, at most
+// MaxConcurrency at a time. The output of each step is held back and
+// printed as a whole once the step is done. In fail-fast mode, the first
+// failure cancels the steps still running and those not started yet. The
+// outcome of every step is returned, along with an error if the whole
+// operation was cancelled meanwhile.
+func (op *Operation) runGroup(ctx context.Context, shellExecutor ShellExecutor, group Step, env []string, position int, failed bool) ([]stepRun, error) {
+	logger := logging.FromContext(ctx)
+	stream := outputs.FromContext(ctx)
+
+	limit := group.MaxConcurrency
+	if limit <= 0 || limit > len(group.Parallel) {
+		limit = len(group.Parallel)
+	}
+	logger.Debugf("Running %d step(s) with a concurrency of %d", len(group.Parallel), limit)
+
+	groupCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	runs := make([]stepRun, len(group.Parallel))
+	slots := make(chan struct{}, limit)
+	var printMu sync.Mutex
+	var wg sync.WaitGroup
+	skip := make([]bool, len(group.Parallel))
+	for idx, step := range group.Parallel {
+		run, err := step.shouldRun(false, failed)
+		if err != nil {
+			return nil, fmt.Errorf("step '%s': %w", step.Label(), err)
+		}
+		skip[idx] = !run
+	}
+	for idx, step := range group.Parallel {
+		if skip[idx] {
+			logger.Infof("Skipping step '%s'", step.Label())
+			runs[idx] = stepRun{step: step, skipped: true}
+			continue
+		}
+		// Steps start in order, as soon as a slot is free.
+		acquired := false
+		select {
+		case slots <- struct{}{}:
+			acquired = true
+		case <-groupCtx.Done():
+		}
+		if groupCtx.Err() != nil {
+			if acquired {
+				<-slots
+			}
+			runs[idx] = stepRun{step: step, halted: true}
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			recorder := outputs.NewRecorder()
+			stepCtx := outputs.AddToContext(groupCtx, recorder.Stream(stream))
+			runs[idx] = op.execStep(stepCtx, shellExecutor, step, env)
+
+			var cancelled *executor.CancelledError
+			if errors.As(runs[idx].err, &cancelled) && errors.Is(context.Cause(groupCtx), errSiblingFailed) {
+				runs[idx].halted = true
+			} else if op.FailFast && !step.ContinueOnError && (runs[idx].err != nil || runs[idx].result.ExitCode != 0) {
+				cancel(errSiblingFailed)
+			}
+
+			printMu.Lock()
+			defer printMu.Unlock()
+			_, _ = fmt.Fprintf(stream.Stdout, "[%d.%d] %s\n", position, idx+1, step.Label())
+			if err := recorder.Replay(stream); err != nil {
+				logger.Warnf("Failed to print the output of step '%s': %v", step.Label(), err)
+			}
+		}()
+	}
+	wg.Wait()
+	if ctx.Err() != nil {
+		return runs, fmt.Errorf("operation cancelled during step '%s': %w", group.Label(), context.Cause(ctx))
+	}
+	return runs, nil
+}
@@ -0,0 +1,138 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gtithub.com/jgfranco17/opsrunner/cli/executor"
+	"gtithub.com/jgfranco17/opsrunner/cli/outputs"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+// parallelExecutor is safe for concurrent use. Commands starting with
+// "fail" exit with code 1 and commands starting with "wait" block until
+// cancelled; every other command writes two lines of output.
+type parallelExecutor struct {
+	mu            sync.Mutex
+	commands      []string
+	running       int
+	maxConcurrent int
+}
+
+func (p *parallelExecutor) Exec(ctx context.Context, command executor.Command) (executor.Result, error) {
+	p.mu.Lock()
+	p.commands = append(p.commands, command.Run)
+	p.running++
+	p.maxConcurrent = max(p.maxConcurrent, p.running)
+	p.mu.Unlock()
+	defer func() {
+		p.mu.Lock()
+		p.running--
+		p.mu.Unlock()
+	}()
+
+	switch {
+	case strings.HasPrefix(command.Run, "fail"):
+		return executor.Result{ExitCode: 1}, nil
+	case strings.HasPrefix(command.Run, "wait"):
+		<-ctx.Done()
+		return executor.Result{ExitCode: -1}, &executor.CancelledError{Cause: context.Cause(ctx)}
+	}
+	for i := 1; i <= 2; i++ {
+		_, _ = fmt.Fprintf(command.Stdout, "%s: line %d\n", command.Run, i)
+		time.Sleep(5 * time.Millisecond)
+	}
+	return executor.Result{}, nil
+}
+
+func TestParallelGroupUnmarshal(t *testing.T) {
+	content := `
+steps:
+  - parallel:
+      - go vet ./...
+      - name: Unit tests
+        run: go test ./...
+    max_concurrency: 1
+  - go build ./...
+`
+	var op Operation
+	require.NoError(t, yaml.Unmarshal([]byte(content), &op))
+	require.Len(t, op.Steps, 2)
+	assert.Equal(t, []Step{{Run: "go vet ./..."}, {Name: "Unit tests", Run: "go test ./..."}}, op.Steps[0].Parallel)
+	assert.Equal(t, 1, op.Steps[0].MaxConcurrency)
+	assert.Equal(t, "parallel group (2 steps)", op.Steps[0].Label())
+}
+
+func TestOperationRunParallelGroup(t *testing.T) {
+	var out bytes.Buffer
+	ctx := outputs.AddToContext(context.Background(), &outputs.Stream{Stdout: &out, Stderr: &out})
+	exec := &parallelExecutor{}
+	op := Operation{
+		Steps: []Step{
+			{Parallel: []Step{{Run: "lint"}, {Run: "vet"}, {Run: "test"}}, MaxConcurrency: 2},
+			{Run: "build"},
+		},
+	}
+	require.NoError(t, op.Run(ctx, exec))
+	assert.ElementsMatch(t, []string{"lint", "vet", "test"}, exec.commands[:3])
+	assert.Equal(t, "build", exec.commands[3])
+	assert.Equal(t, 2, exec.maxConcurrent)
+
+	// The output of each step is printed in one piece.
+	for _, name := range []string{"lint", "vet", "test"} {
+		assert.Contains(t, out.String(), fmt.Sprintf("%s: line 1\n%s: line 2\n", name, name))
+	}
+}
+
+func TestOperationRunParallelGroupFailFast(t *testing.T) {
+	exec := &parallelExecutor{}
+	op := Operation{
+		FailFast: true,
+		Steps: []Step{
+			{Parallel: []Step{{Run: "wait"}, {Run: "fail"}, {Run: "never started"}}, MaxConcurrency: 2},
+			{Run: "build"},
+		},
+	}
+	err := op.Run(context.Background(), exec)
+	assert.EqualError(t, err, "error while running 'fail' (exit code 1, 1 attempt(s))")
+	assert.ElementsMatch(t, []string{"wait", "fail"}, exec.commands)
+}
+
+func TestOperationRunParallelGroupWithoutFailFast(t *testing.T) {
+	exec := &parallelExecutor{}
+	op := Operation{
+		Steps: []Step{
+			{Parallel: []Step{{Run: "fail lint"}, {Run: "test"}}},
+			{Run: "build"},
+		},
+	}
+	err := op.Run(context.Background(), exec)
+	assert.EqualError(t, err, "failed to run steps: [fail lint]")
+	assert.Len(t, exec.commands, 3)
+}
+
+func TestValidateFail_ParallelGroups(t *testing.T) {
+	diags := validationDiagnostics(t, `---
+name: demo
+version: 1.0.0
+codebase:
+  build:
+    steps:
+      - run: make
+        parallel: [make lint]
+      - parallel:
+          - parallel: [make test]
+`)
+	assert.Equal(t, []string{
+		"6:5: codebase.build.steps: step 1 cannot define both run and parallel",
+		"6:5: codebase.build.steps: step 2.1: parallel groups cannot be nested",
+	}, diags)
+}
@@ -0,0 +1,100 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"os/exec"
+	"strings"
+)
+
+// preset holds the defaults of a language, used for the codebase
+// operations that define no steps.
+type preset struct {
+	Dependencies string
+	Requires     []string
+	Env          map[string]string
+	Install      []Step
+	Build        []Step
+}
+
+var presets = map[Language]preset{
+	LanguageGo: {
+		Dependencies: "go.mod,go.sum",
+		Requires:     []string{"go"},
+		Env:          map[string]string{"GOFLAGS": "-mod=readonly"},
+		Install:      []Step{{Run: "go mod download"}},
+		Build:        []Step{{Run: "go build ./..."}},
+	},
+	LanguagePython: {
+		Dependencies: "requirements.txt",
+		Requires:     []string{"python3"},
+		Env:          map[string]string{"PYTHONUNBUFFERED": "1", "PIP_DISABLE_PIP_VERSION_CHECK": "1"},
+		Install:      []Step{{Run: "python3 -m pip install -r requirements.txt"}},
+		Build:        []Step{{Run: "python3 -m compileall -q ."}},
+	},
+	LanguageNode: {
+		Dependencies: "package.json,package-lock.json",
+		Requires:     []string{"node", "npm"},
+		Env:          map[string]string{"NPM_CONFIG_FUND": "false", "NPM_CONFIG_UPDATE_NOTIFIER": "false"},
+		Install:      []Step{{Run: "npm ci"}},
+		Build:        []Step{{Run: "npm run build --if-present"}},
+	},
+	LanguageRust: {
+		Dependencies: "Cargo.toml,Cargo.lock",
+		Requires:     []string{"cargo"},
+		Env:          map[string]string{"CARGO_TERM_COLOR": "always"},
+		Install:      []Step{{Run: "cargo fetch"}},
+		Build:        []Step{{Run: "cargo build --release"}},
+	},
+	LanguageJava: {
+		Dependencies: "pom.xml",
+		Requires:     []string{"mvn"},
+		Env:          map[string]string{"MAVEN_ARGS": "--batch-mode"},
+		Install:      []Step{{Run: "mvn dependency:resolve"}},
+		Build:        []Step{{Run: "mvn package -DskipTests"}},
+	},
+}
+
+// applyPreset fills in the install and build operations that define no
+// steps with the defaults of the codebase language. The preset environment
+// is added below the variables of the operation, and the dependency files
+// of the language are used unless others are declared.
+func (c *Codebase) applyPreset() {
+	preset, ok := presets[c.Language]
+	if !ok {
+		return
+	}
+	if c.Dependencies == "" && len(c.Install.Steps) == 0 {
+		c.Dependencies = preset.Dependencies
+	}
+	for _, target := range []struct {
+		op    *Operation
+		steps []Step
+	}{
+		{&c.Install, preset.Install},
+		{&c.Build, preset.Build},
+	} {
+		if len(target.op.Steps) > 0 {
+			continue
+		}
+		target.op.Steps = append([]Step{}, target.steps...)
+		target.op.Requires = append(append([]string{}, preset.Requires...), target.op.Requires...)
+		env := maps.Clone(preset.Env)
+		maps.Copy(env, target.op.Env)
+		target.op.Env = env
+	}
+}
+
+// checkRequires reports the executables of the list that are not on PATH.
+func checkRequires(requires []string) error {
+	var missing []string
+	for _, tool := range requires {
+		if _, err := exec.LookPath(tool); err != nil {
+			missing = append(missing, tool)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("required tool(s) not found on PATH: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadAppliesLanguagePreset(t *testing.T) {
+	content := `
+name: service
+version: 1.0.0
+codebase:
+  language: go
+`
+	cfg, err := Load(strings.NewReader(content))
+	require.NoError(t, err)
+	require.NoError(t, cfg.Validate())
+	assert.Equal(t, []Step{{Run: "go mod download"}}, cfg.Codebase.Install.Steps)
+	assert.Equal(t, []Step{{Run: "go build ./..."}}, cfg.Codebase.Build.Steps)
+	assert.Equal(t, []string{"go"}, cfg.Codebase.Build.Requires)
+	assert.Equal(t, map[string]string{"GOFLAGS": "-mod=readonly"}, cfg.Codebase.Build.Env)
+}
+
+func TestLoadKeepsDefinedOperations(t *testing.T) {
+	content := `
+name: service
+version: 1.0.0
+codebase:
+  language: python
+  dependencies: requirements*.txt,setup.cfg
+  build:
+    env:
+      PYTHONUNBUFFERED: "0"
+    steps: [python3 -m build]
+`
+	cfg, err := Load(strings.NewReader(content))
+	require.NoError(t, err)
+	assert.Equal(t, []Step{{Run: "python3 -m pip install -r requirements.txt"}}, cfg.Codebase.Install.Steps)
+	assert.Equal(t, "requirements*.txt,setup.cfg", cfg.Codebase.Dependencies)
+	assert.Equal(t, "1", cfg.Codebase.Install.Env["PYTHONUNBUFFERED"])
+	assert.Equal(t, []Step{{Run: "python3 -m build"}}, cfg.Codebase.Build.Steps)
+	assert.Equal(t, map[string]string{"PYTHONUNBUFFERED": "0"}, cfg.Codebase.Build.Env)
+	assert.Empty(t, cfg.Codebase.Build.Requires)
+}
+
+func TestPresetsCoverSupportedLanguages(t *testing.T) {
+	for _, language := range SupportedLanguages {
+		preset, ok := presets[language]
+		if assert.True(t, ok, "no preset for %s", language) {
+			assert.NotEmpty(t, preset.Requires)
+			assert.NotEmpty(t, preset.Install)
+			assert.NotEmpty(t, preset.Build)
+		}
+	}
+}
+
+func TestOperationRunChecksRequiredTools(t *testing.T) {
+	exec := &fakeExecutor{}
+	op := Operation{
+		Requires: []string{"sh", "opsrunner-missing-tool"},
+		Steps:    []Step{{Run: "make"}},
+	}
+	err := op.Run(context.Background(), exec)
+	assert.EqualError(t, err, "required tool(s) not found on PATH: opsrunner-missing-tool")
+	assert.Empty(t, exec.commands)
+}
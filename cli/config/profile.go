@@ -0,0 +1,91 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ProfileEnvVar names the environment variable selecting a profile when
+// none is requested explicitly.
+const ProfileEnvVar = "OPSRUNNER_PROFILE"
+
+// ciProfile is selected automatically when running in CI, if defined.
+const ciProfile = "ci"
+
+// ciVariables are set by common CI providers.
+var ciVariables = []string{"CI", "GITHUB_ACTIONS", "GITLAB_CI", "BUILDKITE", "CIRCLECI", "JENKINS_URL", "TF_BUILD", "TRAVIS"}
+
+// Profile is an overlay applied on top of the project definition, e.g. to
+// change environment variables or steps between local and CI runs. It is
+// merged like an included file: mappings key by key, any other value,
+// including step lists, replaced as a whole.
+type Profile struct {
+	NoInstall bool              `yaml:"no_install,omitempty" desc:"Skip the codebase install operation"`
+	Vars      map[string]string `yaml:"vars,omitempty" desc:"Variables overriding those of the project"`
+	Codebase  Codebase          `yaml:"codebase,omitempty" desc:"Overrides of the codebase operations"`
+	Tasks     map[string]Task   `yaml:"tasks,omitempty" desc:"Overrides of the tasks, or additional tasks"`
+	Hooks     *Hooks            `yaml:"hooks,omitempty" desc:"Overrides of the project hooks"`
+}
+
+// LoadOptions adjust how a definition file is loaded.
+type LoadOptions struct {
+	// Profile is the profile to apply. When empty, the profile named by
+	// OPSRUNNER_PROFILE is used, or the ci profile when running in CI.
+	Profile string
+}
+
+// selectProfile returns the name of the profile to apply to the document,
+// or an empty string if none.
+func selectProfile(requested string, root *yaml.Node) (string, error) {
+	var profiles *yaml.Node
+	if root != nil && root.Kind == yaml.MappingNode {
+		profiles = mappingValue(root, "profiles")
+	}
+	var defined []string
+	if profiles != nil && profiles.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(profiles.Content); i += 2 {
+			defined = append(defined, profiles.Content[i].Value)
+		}
+	}
+	sort.Strings(defined)
+
+	name := requested
+	if name == "" {
+		name = os.Getenv(ProfileEnvVar)
+	}
+	if name == "" {
+		if runningInCI() && profiles != nil && mappingValue(profiles, ciProfile) != nil {
+			return ciProfile, nil
+		}
+		return "", nil
+	}
+	if profiles == nil || mappingValue(profiles, name) == nil {
+		if len(defined) == 0 {
+			return "", fmt.Errorf("profile '%s' not found: no profiles defined in the configuration", name)
+		}
+		return "", fmt.Errorf("profile '%s' not found (available: %s)", name, strings.Join(defined, ", "))
+	}
+	return name, nil
+}
+
+// applyProfile merges the named profile on top of the root mapping.
+func (l *loader) applyProfile(root *yaml.Node, name string) *yaml.Node {
+	overlay := mappingValue(mappingValue(root, "profiles"), name)
+	if overlay.Kind != yaml.MappingNode {
+		return root
+	}
+	return l.merge(root, withoutKey(overlay, "no_install"))
+}
+
+func runningInCI() bool {
+	for _, name := range ciVariables {
+		if value, ok := os.LookupEnv(name); ok && value != "" && value != "false" {
+			return true
+		}
+	}
+	return false
+}
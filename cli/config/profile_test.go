@@ -0,0 +1,141 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const profilesConfig = `
+name: demo
+version: 1.0.0
+vars:
+  mode: debug
+codebase:
+  install:
+    steps: [go mod download]
+  build:
+    env:
+      LOG_LEVEL: debug
+      GOFLAGS: -mod=mod
+    steps:
+      - go build -tags ${{ vars.mode }} ./...
+profiles:
+  ci:
+    no_install: true
+    vars:
+      mode: release
+    codebase:
+      build:
+        env:
+          LOG_LEVEL: info
+  staging:
+    codebase:
+      build:
+        steps:
+          - go build ./...
+          - ./deploy.sh staging
+`
+
+func clearCIVariables(t *testing.T) {
+	t.Helper()
+	for _, name := range append(ciVariables, ProfileEnvVar) {
+		t.Setenv(name, "")
+	}
+}
+
+func loadProfile(t *testing.T, profile string) *ProjectDefinition {
+	t.Helper()
+	dir := writeFiles(t, map[string]string{"opsrunner.yaml": profilesConfig})
+	cfg, err := LoadFile(filepath.Join(dir, "opsrunner.yaml"), &LoadOptions{Profile: profile})
+	require.NoError(t, err)
+	return cfg
+}
+
+func TestLoadWithoutProfile(t *testing.T) {
+	clearCIVariables(t)
+	cfg := loadProfile(t, "")
+	assert.Empty(t, cfg.ActiveProfile)
+	assert.Equal(t, []Step{{Run: "go build -tags debug ./..."}}, cfg.Codebase.Build.Steps)
+}
+
+func TestLoadAppliesProfile(t *testing.T) {
+	clearCIVariables(t)
+	cfg := loadProfile(t, "ci")
+	assert.Equal(t, "ci", cfg.ActiveProfile)
+	assert.Equal(t, map[string]string{"LOG_LEVEL": "info", "GOFLAGS": "-mod=mod"}, cfg.Codebase.Build.Env)
+	assert.Equal(t, []Step{{Run: "go build -tags release ./..."}}, cfg.Codebase.Build.Steps)
+
+	cfg = loadProfile(t, "staging")
+	assert.Equal(t, []Step{{Run: "go build ./..."}, {Run: "./deploy.sh staging"}}, cfg.Codebase.Build.Steps)
+	require.NoError(t, cfg.Validate())
+}
+
+func TestLoadSelectsProfileFromEnvironment(t *testing.T) {
+	clearCIVariables(t)
+	t.Setenv(ProfileEnvVar, "staging")
+	assert.Equal(t, "staging", loadProfile(t, "").ActiveProfile)
+	assert.Equal(t, "ci", loadProfile(t, "ci").ActiveProfile)
+}
+
+func TestLoadDetectsCIProfile(t *testing.T) {
+	clearCIVariables(t)
+	t.Setenv("GITHUB_ACTIONS", "true")
+	assert.Equal(t, "ci", loadProfile(t, "").ActiveProfile)
+
+	t.Setenv("GITHUB_ACTIONS", "false")
+	assert.Empty(t, loadProfile(t, "").ActiveProfile)
+}
+
+func TestLoadUnknownProfile(t *testing.T) {
+	clearCIVariables(t)
+	dir := writeFiles(t, map[string]string{"opsrunner.yaml": profilesConfig})
+	_, err := LoadFile(filepath.Join(dir, "opsrunner.yaml"), &LoadOptions{Profile: "prod"})
+	assert.EqualError(t, err, "profile 'prod' not found (available: ci, staging)")
+}
+
+func TestLoadResolvesSelectedProfileOnly(t *testing.T) {
+	clearCIVariables(t)
+	t.Setenv("DEPLOY_TOKEN", "")
+	require.NoError(t, os.Unsetenv("DEPLOY_TOKEN"))
+	dir := writeFiles(t, map[string]string{"opsrunner.yaml": `
+name: demo
+version: 1.0.0
+codebase:
+  build:
+    steps: [make]
+profiles:
+  prod:
+    vars:
+      token: ${{ env.DEPLOY_TOKEN }}
+    codebase:
+      build:
+        steps:
+          - make deploy TOKEN=${{ vars.token }}
+`})
+	path := filepath.Join(dir, "opsrunner.yaml")
+
+	cfg, err := LoadFile(path, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []Step{{Run: "make"}}, cfg.Codebase.Build.Steps)
+	require.NoError(t, cfg.Validate())
+
+	_, err = LoadFile(path, &LoadOptions{Profile: "prod"})
+	assert.ErrorContains(t, err, "environment variable 'DEPLOY_TOKEN' is not set")
+
+	t.Setenv("DEPLOY_TOKEN", "secret")
+	cfg, err = LoadFile(path, &LoadOptions{Profile: "prod"})
+	require.NoError(t, err)
+	assert.Equal(t, []Step{{Run: "make deploy TOKEN=secret"}}, cfg.Codebase.Build.Steps)
+}
+
+func TestBuildSkipsInstallForProfile(t *testing.T) {
+	clearCIVariables(t)
+	exec := &fakeExecutor{}
+	require.NoError(t, Build(context.Background(), exec, loadProfile(t, "ci"), nil))
+	assert.Equal(t, []string{"go build -tags release ./..."}, exec.commands)
+}
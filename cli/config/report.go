@@ -0,0 +1,60 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"gtithub.com/jgfranco17/opsrunner/cli/outputs"
+)
+
+type stepStatus string
+
+const (
+	stepPassed    stepStatus = "passed"
+	stepFailed    stepStatus = "failed"
+	stepIgnored   stepStatus = "failed (ignored)"
+	stepTimedOut  stepStatus = "timed out"
+	stepCancelled stepStatus = "cancelled"
+	stepSkipped   stepStatus = "skipped"
+)
+
+// stepResult records the outcome of a single step for the summary printed
+// at the end of an operation.
+type stepResult struct {
+	Label    string
+	Status   stepStatus
+	ExitCode int
+	Attempts int
+	Duration time.Duration
+}
+
+func (r stepResult) String() string {
+	var details []string
+	if r.Status != stepPassed && r.Status != stepCancelled && r.ExitCode > 0 {
+		details = append(details, fmt.Sprintf("exit code %d", r.ExitCode))
+	}
+	if r.Attempts > 1 {
+		details = append(details, fmt.Sprintf("%d attempts", r.Attempts))
+	}
+	if r.Status == stepSkipped {
+		return fmt.Sprintf("%s: %s", r.Label, r.Status)
+	}
+	details = append(details, r.Duration.Round(time.Millisecond).String())
+	return fmt.Sprintf("%s: %s (%s)", r.Label, r.Status, strings.Join(details, ", "))
+}
+
+func printStepSummary(results []stepResult) {
+	for _, result := range results {
+		color := "red"
+		switch result.Status {
+		case stepPassed:
+			color = "green"
+		case stepIgnored, stepCancelled:
+			color = "yellow"
+		case stepSkipped:
+			color = "cyan"
+		}
+		outputs.PrintColoredMessage(color, "%s", result.String())
+	}
+}
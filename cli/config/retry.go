@@ -0,0 +1,69 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"gtithub.com/jgfranco17/opsrunner/cli/executor"
+	"gtithub.com/jgfranco17/opsrunner/cli/logging"
+)
+
+// RetryPolicy describes how a failing step is retried.
+type RetryPolicy struct {
+	Attempts    int           `yaml:"attempts" required:"true" desc:"Maximum number of attempts, including the first one"`
+	Delay       time.Duration `yaml:"delay,omitempty" desc:"Wait time before the second attempt, e.g. 2s"`
+	Backoff     float64       `yaml:"backoff,omitempty" desc:"Multiplier applied to the delay after every attempt, e.g. 2"`
+	OnExitCodes []int         `yaml:"on_exit_codes,omitempty" desc:"Only retry on these exit codes, any failure is retried by default"`
+}
+
+// shouldRetry reports whether a failed attempt is eligible for a retry.
+// Steps stopped by a cancellation or by an operation or run timeout are
+// never retried.
+func (r *RetryPolicy) shouldRetry(result executor.Result, err error) bool {
+	var timeoutErr *TimeoutError
+	var cancelled *executor.CancelledError
+	if errors.As(err, &timeoutErr) {
+		if timeoutErr.Scope != timeoutScopeStep {
+			return false
+		}
+	} else if errors.As(err, &cancelled) {
+		return false
+	}
+	return len(r.OnExitCodes) == 0 || slices.Contains(r.OnExitCodes, result.ExitCode)
+}
+
+// runStepWithRetry runs the step, retrying it according to its retry
+// policy or, if it has none, the one of the operation. It returns the
+// result of the last attempt and the number of attempts made.
+func (op *Operation) runStepWithRetry(ctx context.Context, shellExecutor ShellExecutor, step Step, env []string) (executor.Result, int, error) {
+	logger := logging.FromContext(ctx)
+
+	policy := step.Retry
+	if policy == nil {
+		policy = op.Retry
+	}
+	maxAttempts := 1
+	var delay time.Duration
+	if policy != nil {
+		maxAttempts = max(policy.Attempts, 1)
+		delay = policy.Delay
+	}
+	for attempt := 1; ; attempt++ {
+		result, err := op.runStep(ctx, shellExecutor, step, env)
+		if (err == nil && result.ExitCode == 0) || attempt >= maxAttempts || !policy.shouldRetry(result, err) {
+			return result, attempt, err
+		}
+		logger.Warnf("Step '%s' failed (exit code %d), retrying in %s (attempt %d of %d)",
+			step.Label(), result.ExitCode, delay, attempt+1, maxAttempts)
+		select {
+		case <-ctx.Done():
+			return result, attempt, err
+		case <-time.After(delay):
+		}
+		if policy.Backoff > 1 {
+			delay = time.Duration(float64(delay) * policy.Backoff)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package config
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestRetryPolicyUnmarshal(t *testing.T) {
+	content := `
+retry:
+  attempts: 3
+  delay: 2s
+  backoff: 1.5
+  on_exit_codes: [1, 137]
+steps:
+  - run: go mod download
+    retry:
+      attempts: 5
+`
+	var op Operation
+	require.NoError(t, yaml.Unmarshal([]byte(content), &op))
+	assert.Equal(t, &RetryPolicy{Attempts: 3, Delay: 2 * time.Second, Backoff: 1.5, OnExitCodes: []int{1, 137}}, op.Retry)
+	assert.Equal(t, &RetryPolicy{Attempts: 5}, op.Steps[0].Retry)
+}
+
+func TestOperationRunRetriesUntilSuccess(t *testing.T) {
+	exec := &fakeExecutor{exitSequences: map[string][]int{"go mod download": {1, 1, 0}}}
+	op := Operation{
+		FailFast: true,
+		Retry:    &RetryPolicy{Attempts: 3, Delay: time.Millisecond, Backoff: 2},
+		Steps:    []Step{{Run: "go mod download"}, {Run: "go build"}},
+	}
+	require.NoError(t, op.Run(context.Background(), exec))
+	assert.Equal(t, []string{"go mod download", "go mod download", "go mod download", "go build"}, exec.commands)
+}
+
+func TestOperationRunRetryExhausted(t *testing.T) {
+	exec := &fakeExecutor{exitCodes: map[string]int{"flaky": 2}}
+	op := Operation{
+		FailFast: true,
+		Steps:    []Step{{Run: "flaky", Retry: &RetryPolicy{Attempts: 2}}},
+	}
+	err := op.Run(context.Background(), exec)
+	assert.EqualError(t, err, "error while running 'flaky' (exit code 2, 2 attempt(s))")
+	assert.Len(t, exec.commands, 2)
+}
+
+func TestOperationRunRetryOnlyOnListedExitCodes(t *testing.T) {
+	exec := &fakeExecutor{exitCodes: map[string]int{"make": 2}}
+	op := Operation{
+		Retry: &RetryPolicy{Attempts: 3, OnExitCodes: []int{137}},
+		Steps: []Step{{Run: "make"}},
+	}
+	assert.ErrorContains(t, op.Run(context.Background(), exec), "failed to run steps: [make]")
+	assert.Len(t, exec.commands, 1)
+}
+
+func TestStepResultString(t *testing.T) {
+	result := stepResult{Label: "test", Status: stepFailed, ExitCode: 1, Attempts: 3, Duration: 1500 * time.Millisecond}
+	assert.Equal(t, "test: failed (exit code 1, 3 attempts, 1.5s)", result.String())
+}
@@ -0,0 +1,108 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"time"
+)
+
+const schemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// durationPattern matches the strings accepted by time.ParseDuration.
+const durationPattern = `^([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$`
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// schemaEnum is implemented by types that only accept a fixed set of values.
+type schemaEnum interface {
+	enumValues() []string
+}
+
+// schemaExtender is implemented by types whose YAML form differs from
+// their struct layout, e.g. to accept a shorthand notation.
+type schemaExtender interface {
+	extendSchema(schema map[string]any) map[string]any
+}
+
+// JSONSchema generates a JSON Schema document describing the OpsRunner
+// definition file, derived from the config types and their field tags.
+func JSONSchema() ([]byte, error) {
+	generator := &schemaGenerator{defs: make(map[string]any)}
+	root := generator.structSchema(reflect.TypeOf(ProjectDefinition{}))
+	root["$schema"] = schemaDialect
+	root["title"] = "OpsRunner definition file"
+	root["$defs"] = generator.defs
+	return json.MarshalIndent(root, "", "  ")
+}
+
+type schemaGenerator struct {
+	defs map[string]any
+}
+
+func (g *schemaGenerator) schemaFor(t reflect.Type) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	var schema map[string]any
+	switch {
+	case t == durationType:
+		schema = map[string]any{"type": "string", "pattern": durationPattern}
+	case t.Kind() == reflect.Struct:
+		name := t.Name()
+		if _, exists := g.defs[name]; !exists {
+			// Reserve the name first so recursive types terminate.
+			g.defs[name] = nil
+			definition := g.structSchema(t)
+			if extender, ok := reflect.Zero(t).Interface().(schemaExtender); ok {
+				definition = extender.extendSchema(definition)
+			}
+			g.defs[name] = definition
+		}
+		schema = map[string]any{"$ref": "#/$defs/" + name}
+	case t.Kind() == reflect.String:
+		schema = map[string]any{"type": "string"}
+	case t.Kind() == reflect.Bool:
+		schema = map[string]any{"type": "boolean"}
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		schema = map[string]any{"type": "integer"}
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		schema = map[string]any{"type": "number"}
+	case t.Kind() == reflect.Map:
+		schema = map[string]any{"type": "object", "additionalProperties": g.schemaFor(t.Elem())}
+	case t.Kind() == reflect.Slice:
+		schema = map[string]any{"type": "array", "items": g.schemaFor(t.Elem())}
+	default:
+		schema = map[string]any{}
+	}
+	if enum, ok := reflect.Zero(t).Interface().(schemaEnum); ok {
+		schema["enum"] = enum.enumValues()
+	}
+	return schema
+}
+
+func (g *schemaGenerator) structSchema(t reflect.Type) map[string]any {
+	properties := make(map[string]any)
+	var required []string
+	for _, field := range yamlFieldList(t) {
+		property := g.schemaFor(field.Type)
+		if desc := field.Tag.Get("desc"); desc != "" {
+			property["description"] = desc
+		}
+		properties[field.Key] = property
+		if field.Tag.Get("required") == "true" {
+			required = append(required, field.Key)
+		}
+	}
+	schema := map[string]any{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+	if inline := inlineMap(t); inline != nil {
+		schema["additionalProperties"] = g.schemaFor(inline.Elem())
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
@@ -0,0 +1,46 @@
+package config
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONSchema(t *testing.T) {
+	content, err := JSONSchema()
+	require.NoError(t, err)
+
+	var schema map[string]any
+	require.NoError(t, json.Unmarshal(content, &schema))
+	assert.Equal(t, schemaDialect, schema["$schema"])
+	assert.Equal(t, false, schema["additionalProperties"])
+	assert.ElementsMatch(t, []any{"name", "version"}, schema["required"])
+
+	properties := schema["properties"].(map[string]any)
+	assert.Equal(t, "#/$defs/Codebase", properties["codebase"].(map[string]any)["$ref"])
+	assert.Equal(t, "Name of the project", properties["name"].(map[string]any)["description"])
+
+	defs := schema["$defs"].(map[string]any)
+	assert.Contains(t, defs, "Operation")
+
+	codebase := defs["Codebase"].(map[string]any)["properties"].(map[string]any)
+	assert.Equal(t, []any{"go", "python", "node", "rust", "java"}, codebase["language"].(map[string]any)["enum"])
+
+	// Task inlines the operation fields next to its own.
+	task := defs["Task"].(map[string]any)["properties"].(map[string]any)
+	assert.Contains(t, task, "depends_on")
+	assert.Contains(t, task, "steps")
+
+	// Steps accept the shorthand string form as well as the mapping form.
+	step := defs["Step"].(map[string]any)["oneOf"].([]any)
+	assert.Equal(t, "string", step[0].(map[string]any)["type"])
+	stepObject := step[1].(map[string]any)
+	assert.Equal(t, []any{
+		map[string]any{"required": []any{"run"}},
+		map[string]any{"required": []any{"parallel"}},
+	}, stepObject["oneOf"])
+	timeout := stepObject["properties"].(map[string]any)["timeout"].(map[string]any)
+	assert.Equal(t, durationPattern, timeout["pattern"])
+}
@@ -0,0 +1,65 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gtithub.com/jgfranco17/opsrunner/cli/executor"
+)
+
+func TestOperationRunSession(t *testing.T) {
+	dir := t.TempDir()
+	result := filepath.Join(dir, "result")
+	op := Operation{
+		Session: true,
+		Steps: []Step{
+			{Run: "cd " + dir},
+			{Run: "export GREETING=hello"},
+			{Name: "tag", Run: `echo "tag=v1" >> "$OPSRUNNER_OUTPUT"`},
+			{Run: `echo "$GREETING ${{ steps.tag.outputs.tag }} $(pwd)" > result`},
+		},
+	}
+	require.NoError(t, op.Run(context.Background(), &executor.DefaultExecutor{}))
+
+	content, err := os.ReadFile(result)
+	require.NoError(t, err)
+	assert.Equal(t, "hello v1 "+dir+"\n", string(content))
+}
+
+func TestOperationRunSessionTimeoutFailsLaterSteps(t *testing.T) {
+	op := Operation{
+		Session: true,
+		Steps: []Step{
+			{Name: "hang", Run: "sleep 5", Timeout: 100 * time.Millisecond},
+			{Name: "after", Run: "true"},
+		},
+	}
+	ctx := executor.WithGracePeriod(context.Background(), 100*time.Millisecond)
+	err := op.Run(ctx, &executor.DefaultExecutor{})
+	assert.ErrorContains(t, err, "failed to run steps: [hang after]")
+}
+
+func TestOperationRunSessionRequiresSupport(t *testing.T) {
+	op := Operation{Session: true, Steps: []Step{{Run: "true"}}}
+	err := op.Run(context.Background(), &fakeExecutor{})
+	assert.EqualError(t, err, "the executor does not support shell sessions")
+}
+
+func TestValidateFail_SessionParallelGroup(t *testing.T) {
+	diags := validationDiagnostics(t, `---
+name: demo
+version: 1.0.0
+codebase:
+  build:
+    session: true
+    steps:
+      - parallel: [make lint, make test]
+`)
+	assert.Equal(t, []string{"7:5: codebase.build.steps: step 1: parallel groups cannot run in a shell session"}, diags)
+}
@@ -0,0 +1,96 @@
+package config
+
+import (
+	"fmt"
+
+	"gtithub.com/jgfranco17/opsrunner/cli/executor"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Shell selects the program running the steps. In YAML it can be written
+// either as a plain string holding the command, or as a mapping that can
+// also enable strict mode. The shell of a step overrides the one of its
+// operation, which overrides the one of the project.
+type Shell struct {
+	Command string `yaml:"command" required:"true" desc:"Known shell (bash, sh, zsh, python3, node) or a template like \"python3 {0}\", where {0} is the script file"`
+	Strict  bool   `yaml:"strict,omitempty" desc:"Stop the script at the first failing command, e.g. with set -euo pipefail"`
+}
+
+// shellFields mirrors Shell without its YAML methods.
+type shellFields Shell
+
+// UnmarshalYAML accepts both the string and the mapping form of a shell.
+func (s *Shell) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		*s = Shell{Command: node.Value}
+		return nil
+	case yaml.MappingNode:
+		var fields shellFields
+		if err := node.Decode(&fields); err != nil {
+			return err
+		}
+		*s = Shell(fields)
+		return nil
+	default:
+		return fmt.Errorf("line %d: shell must be a string or a mapping", node.Line)
+	}
+}
+
+// MarshalYAML writes shells without strict mode back in the string form.
+func (s Shell) MarshalYAML() (interface{}, error) {
+	if !s.Strict {
+		return s.Command, nil
+	}
+	return shellFields(s), nil
+}
+
+func (s Shell) extendSchema(schema map[string]any) map[string]any {
+	return map[string]any{
+		"oneOf": []any{
+			map[string]any{"type": "string", "description": "Known shell or a template like \"python3 {0}\""},
+			schema,
+		},
+	}
+}
+
+// check reports whether the shell can run the steps, and whether it can
+// do so in a shell session.
+func (s *Shell) check(session bool) error {
+	if err := executor.CheckShell(s.Command, s.Strict); err != nil {
+		return err
+	}
+	if session && s.Command != executor.DefaultShell {
+		return fmt.Errorf("shell sessions only run %s, not '%s'", executor.DefaultShell, s.Command)
+	}
+	if session && s.Strict {
+		return fmt.Errorf("strict mode is not supported in shell sessions")
+	}
+	return nil
+}
+
+// applyShell gives the project shell to the operations that define none.
+func (p *ProjectDefinition) applyShell() {
+	if p.Shell == nil {
+		return
+	}
+	var apply func(op *Operation)
+	apply = func(op *Operation) {
+		if op.Shell == nil {
+			op.Shell = p.Shell
+		}
+		for _, hook := range op.Hooks.hooks() {
+			apply(hook.Operation)
+		}
+	}
+	for _, hook := range p.Hooks.hooks() {
+		apply(hook.Operation)
+	}
+	apply(&p.Codebase.Install)
+	apply(&p.Codebase.Build)
+	for name, task := range p.Tasks {
+		apply(&task.Operation)
+		p.Tasks[name] = task
+	}
+}
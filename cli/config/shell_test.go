@@ -0,0 +1,136 @@
+package config
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestShellUnmarshal(t *testing.T) {
+	content := `
+shell: sh
+steps:
+  - echo default
+  - run: |
+      import platform
+      print(platform.system())
+    shell: python3
+  - run: make test
+    shell:
+      command: bash
+      strict: true
+`
+	var op Operation
+	require.NoError(t, yaml.Unmarshal([]byte(content), &op))
+	assert.Equal(t, &Shell{Command: "sh"}, op.Shell)
+	assert.Nil(t, op.Steps[0].Shell)
+	assert.Equal(t, &Shell{Command: "python3"}, op.Steps[1].Shell)
+	assert.Equal(t, "import platform\nprint(platform.system())\n", op.Steps[1].Run)
+	assert.Equal(t, &Shell{Command: "bash", Strict: true}, op.Steps[2].Shell)
+}
+
+func TestShellMarshal(t *testing.T) {
+	out, err := yaml.Marshal(Operation{Shell: &Shell{Command: "sh"}, Steps: []Step{
+		{Run: "make", Shell: &Shell{Command: "bash", Strict: true}},
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, "shell: sh\nsteps:\n    - run: make\n      shell:\n        command: bash\n        strict: true\n", string(out))
+}
+
+func TestOperationRunPassesShell(t *testing.T) {
+	exec := &fakeExecutor{}
+	op := Operation{
+		Shell: &Shell{Command: "sh", Strict: true},
+		Steps: []Step{
+			{Run: "make"},
+			{Run: "print(1)", Shell: &Shell{Command: "python3"}},
+		},
+	}
+	require.NoError(t, op.Run(context.Background(), exec))
+	require.Len(t, exec.received, 2)
+	assert.Equal(t, "sh", exec.received[0].Shell)
+	assert.True(t, exec.received[0].Strict)
+	assert.Equal(t, "python3", exec.received[1].Shell)
+	assert.False(t, exec.received[1].Strict)
+}
+
+func TestLoadAppliesProjectShell(t *testing.T) {
+	cfg, err := Load(strings.NewReader(`---
+name: demo
+version: 1.0.0
+shell: sh
+codebase:
+  build:
+    steps: [make]
+tasks:
+  lint:
+    shell: zsh
+    steps: [make lint]
+    hooks:
+      after:
+        steps: [echo done]
+`))
+	require.NoError(t, err)
+	assert.Equal(t, "sh", cfg.Codebase.Build.Shell.Command)
+	assert.Equal(t, "sh", cfg.Codebase.Install.Shell.Command)
+	assert.Equal(t, "zsh", cfg.Tasks["lint"].Shell.Command)
+	assert.Equal(t, "sh", cfg.Tasks["lint"].Hooks.After.Shell.Command)
+}
+
+func TestBuildAppliesProjectShell(t *testing.T) {
+	project := &ProjectDefinition{
+		Shell: &Shell{Command: "sh"},
+		Hooks: &Hooks{Before: &Operation{Steps: []Step{{Run: "echo start"}}}},
+		Codebase: Codebase{
+			Build: Operation{Steps: []Step{{Run: "make"}}},
+		},
+		Tasks: map[string]Task{
+			"lint": {Operation: Operation{Steps: []Step{{Run: "make lint"}}}},
+			"docs": {Operation: Operation{Shell: &Shell{Command: "zsh"}, Steps: []Step{{Run: "make docs"}}}},
+		},
+	}
+
+	exec := &fakeExecutor{}
+	require.NoError(t, Build(context.Background(), exec, project, nil))
+	require.NoError(t, RunTask(context.Background(), exec, project, "lint", nil))
+	require.NoError(t, RunTask(context.Background(), exec, project, "docs", nil))
+	var shells []string
+	for _, command := range exec.received {
+		shells = append(shells, command.Run+": "+command.Shell)
+	}
+	assert.Equal(t, []string{
+		"echo start: sh", "make: sh",
+		"echo start: sh", "make lint: sh",
+		"echo start: sh", "make docs: zsh",
+	}, shells)
+}
+
+func TestValidateFail_Shell(t *testing.T) {
+	diags := validationDiagnostics(t, `---
+name: demo
+version: 1.0.0
+shell: fish
+codebase:
+  build:
+    shell: bash
+    steps:
+      - run: print(1)
+        shell:
+          command: python3
+          strict: true
+tasks:
+  docs:
+    session: true
+    shell: sh
+    steps: [make docs]
+`)
+	assert.Equal(t, []string{
+		"4:1: shell: unknown shell 'fish' (expected one of: bash, node, python3, sh, zsh, or a template containing {0})",
+		"8:5: codebase.build.steps: step 1: strict mode is not supported by shell 'python3'",
+		"16:5: tasks.docs.shell: shell sessions only run bash, not 'sh'",
+	}, diags)
+}
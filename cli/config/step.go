@@ -0,0 +1,105 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Step is a single command within an Operation. In YAML a step can be
+// written either as a plain string holding the command, or as a mapping
+// with additional settings. A step holding Parallel steps instead of a
+// command is a group whose steps run concurrently.
+type Step struct {
+	Name            string            `yaml:"name,omitempty" desc:"Display name of the step"`
+	If              string            `yaml:"if,omitempty" desc:"Condition that must hold for the step to run, e.g. os == \"linux\""`
+	Run             string            `yaml:"run,omitempty" desc:"Command to run"`
+	Dir             string            `yaml:"dir,omitempty" desc:"Working directory of the command"`
+	Env             map[string]string `yaml:"env,omitempty" desc:"Environment variables set for this step only"`
+	Timeout         time.Duration     `yaml:"timeout,omitempty" desc:"Maximum run time of the step, e.g. 30s or 5m"`
+	ContinueOnError bool              `yaml:"continue_on_error,omitempty" desc:"Do not fail the operation if this step fails"`
+	Retry           *RetryPolicy      `yaml:"retry,omitempty" desc:"Retry policy of the step, overriding the one of the operation"`
+	Parallel        []Step            `yaml:"parallel,omitempty" desc:"Steps to run concurrently instead of a command"`
+	MaxConcurrency  int               `yaml:"max_concurrency,omitempty" desc:"Maximum number of parallel steps running at once, all by default"`
+	Shell           *Shell            `yaml:"shell,omitempty" desc:"Program running the command, overriding the one of the operation"`
+}
+
+// stepFields mirrors Step without its YAML methods, to allow decoding the
+// mapping form without recursing into UnmarshalYAML.
+type stepFields Step
+
+// UnmarshalYAML accepts both the string and the mapping form of a step.
+func (s *Step) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		var command string
+		if err := node.Decode(&command); err != nil {
+			return err
+		}
+		*s = Step{Run: command}
+		return nil
+	case yaml.MappingNode:
+		var fields stepFields
+		if err := node.Decode(&fields); err != nil {
+			return err
+		}
+		if fields.Run == "" && len(fields.Parallel) == 0 {
+			return fmt.Errorf("line %d: step is missing the 'run' command", node.Line)
+		}
+		*s = Step(fields)
+		return nil
+	default:
+		return fmt.Errorf("line %d: step must be a string or a mapping", node.Line)
+	}
+}
+
+// MarshalYAML writes steps that only hold a command back in the string form.
+func (s Step) MarshalYAML() (interface{}, error) {
+	if s.Name == "" && s.If == "" && s.Dir == "" && len(s.Env) == 0 && s.Timeout == 0 && !s.ContinueOnError && s.Retry == nil &&
+		len(s.Parallel) == 0 && s.MaxConcurrency == 0 && s.Shell == nil {
+		return s.Run, nil
+	}
+	return stepFields(s), nil
+}
+
+// Label returns a human-readable identifier for the step, preferring its
+// name over the raw command. Multi-line scripts are shortened to their
+// first line.
+func (s *Step) Label() string {
+	if s.Name != "" {
+		return s.Name
+	}
+	if s.Run == "" && len(s.Parallel) > 0 {
+		return fmt.Sprintf("parallel group (%d steps)", len(s.Parallel))
+	}
+	script := strings.TrimSpace(s.Run)
+	if first, _, multiline := strings.Cut(script, "\n"); multiline {
+		return strings.TrimSpace(first) + " ..."
+	}
+	return script
+}
+
+// shouldRun evaluates the condition of the step. Once an operation has
+// been aborted, only steps whose condition checks the outcome of earlier
+// steps, such as failure() or always(), may still run.
+func (s *Step) shouldRun(aborted bool, failed bool) (bool, error) {
+	if aborted && !usesStatus(s.If) {
+		return false, nil
+	}
+	return evalCondition(s.If, newExprContext(failed))
+}
+
+func (s Step) extendSchema(schema map[string]any) map[string]any {
+	schema["oneOf"] = []any{
+		map[string]any{"required": []string{"run"}},
+		map[string]any{"required": []string{"parallel"}},
+	}
+	return map[string]any{
+		"oneOf": []any{
+			map[string]any{"type": "string", "description": "Command to run"},
+			schema,
+		},
+	}
+}
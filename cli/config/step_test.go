@@ -0,0 +1,75 @@
+package config
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestStepUnmarshalBothForms(t *testing.T) {
+	content := `
+steps:
+  - go mod tidy
+  - name: Unit tests
+    run: go test ./...
+    dir: cli
+    env:
+      CGO_ENABLED: "0"
+    timeout: 5m
+    continue_on_error: true
+`
+	var op Operation
+	require.NoError(t, yaml.Unmarshal([]byte(content), &op))
+	assert.Equal(t, []Step{
+		{Run: "go mod tidy"},
+		{
+			Name:            "Unit tests",
+			Run:             "go test ./...",
+			Dir:             "cli",
+			Env:             map[string]string{"CGO_ENABLED": "0"},
+			Timeout:         5 * time.Minute,
+			ContinueOnError: true,
+		},
+	}, op.Steps)
+}
+
+func TestStepUnmarshalFail_MissingRun(t *testing.T) {
+	var op Operation
+	err := yaml.Unmarshal([]byte("steps:\n  - name: nothing\n"), &op)
+	assert.ErrorContains(t, err, "step is missing the 'run' command")
+}
+
+func TestStepMarshalKeepsStringForm(t *testing.T) {
+	content, err := yaml.Marshal(Operation{Steps: []Step{{Run: "ls"}, {Name: "list", Run: "ls -la"}}})
+	require.NoError(t, err)
+	assert.True(t, strings.Contains(string(content), "- ls\n"))
+	assert.True(t, strings.Contains(string(content), "name: list"))
+}
+
+func TestOperationRunPassesStepSettings(t *testing.T) {
+	exec := &fakeExecutor{exitCodes: map[string]int{"flaky": 1}}
+	op := Operation{
+		FailFast: true,
+		Steps: []Step{
+			{Run: "flaky", ContinueOnError: true},
+			{Run: "make", Dir: "build", Env: map[string]string{"B": "2", "A": "1"}},
+		},
+	}
+	err := op.Run(context.Background(), exec)
+	assert.NoError(t, err)
+	assert.Equal(t, "make", exec.received[1].Run)
+	assert.Equal(t, "build", exec.received[1].Dir)
+	assert.Subset(t, exec.received[1].Env, []string{"A=1", "B=2"})
+}
+
+func TestStepLabel(t *testing.T) {
+	assert.Equal(t, "lint", (&Step{Name: "lint", Run: "make lint"}).Label())
+	assert.Equal(t, "make lint", (&Step{Run: "make lint"}).Label())
+	assert.Equal(t, "import sys ...", (&Step{Run: "import sys\nprint(sys.argv)\n"}).Label())
+	assert.Equal(t, "parallel group (2 steps)", (&Step{Parallel: []Step{{Run: "a"}, {Run: "b"}}}).Label())
+}
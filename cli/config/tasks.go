@@ -0,0 +1,121 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"gtithub.com/jgfranco17/opsrunner/cli/cache"
+	"gtithub.com/jgfranco17/opsrunner/cli/logging"
+)
+
+// TaskNames returns the names of all defined tasks in alphabetical order.
+func (p *ProjectDefinition) TaskNames() []string {
+	names := make([]string, 0, len(p.Tasks))
+	for name := range p.Tasks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// RunTask executes the named task from the project definition, running
+// each of its prerequisites exactly once beforehand.
+func RunTask(ctx context.Context, shellExecutor ShellExecutor, config *ProjectDefinition, name string, opts *BuildOptions) error {
+	logger := logging.FromContext(ctx)
+	startTime := time.Now()
+
+	if _, ok := config.Tasks[name]; !ok {
+		available := config.TaskNames()
+		if len(available) == 0 {
+			return fmt.Errorf("task '%s' not found: no tasks defined in the configuration", name)
+		}
+		return fmt.Errorf("task '%s' not found (available: %s)", name, strings.Join(available, ", "))
+	}
+	graph, err := NewProjectGraph(shellExecutor, config, opts)
+	if err != nil {
+		return err
+	}
+	err = config.Hooks.around(ctx, shellExecutor, func(ctx context.Context) error {
+		return graph.Execute(ctx, name)
+	})
+	if err != nil {
+		return err
+	}
+	duration := time.Since(startTime)
+	logger.Infof("Task '%s' completed successfully in %dms", name, duration.Milliseconds())
+	return nil
+}
+
+func taskNode(shellExecutor ShellExecutor, name string, task Task, opts *BuildOptions) Node {
+	return Node{
+		Name:      name,
+		DependsOn: task.DependsOn,
+		Run: func(ctx context.Context) error {
+			logger := logging.FromContext(ctx)
+			// Skipped tasks must not be recorded as up to date.
+			if run, err := task.enabled(); err != nil {
+				return fmt.Errorf("failed to run task '%s': %w", name, err)
+			} else if !run {
+				logger.Infof("Skipping task '%s', condition not met: %s", name, task.If)
+				return nil
+			}
+			store := cache.FromContext(ctx)
+			cached := store != nil && len(task.Inputs) > 0
+			if cached && !opts.Force {
+				fingerprint, err := task.fingerprint()
+				if err != nil {
+					logger.Warnf("Not caching task '%s': %v", name, err)
+					cached = false
+				} else if last, _, err := store.Get(taskCacheKey(name)); err != nil {
+					logger.Warnf("Ignoring the cache of task '%s': %v", name, err)
+				} else if last == fingerprint {
+					logger.Infof("Task '%s' is up to date, skipping", name)
+					return nil
+				}
+			}
+			if len(task.Steps) == 0 {
+				logger.Warnf("No steps defined for task '%s'.", name)
+			}
+			logger.Debugf("Running task '%s'", name)
+			if err := task.Run(ctx, shellExecutor); err != nil {
+				return fmt.Errorf("failed to run task '%s': %w", name, err)
+			}
+			if cached {
+				// Outputs are only complete once the task succeeded.
+				fingerprint, err := task.fingerprint()
+				if err == nil {
+					err = store.Put(taskCacheKey(name), fingerprint)
+				}
+				if err != nil {
+					logger.Warnf("Failed to record task '%s': %v", name, err)
+				}
+			}
+			return nil
+		},
+	}
+}
+
+func taskCacheKey(name string) string {
+	return "task:" + name
+}
+
+// fingerprint hashes the inputs and outputs of the task together with its
+// definition, including its steps, hooks and environment settings.
+func (t *Task) fingerprint() (string, error) {
+	definition, err := t.definition()
+	if err != nil {
+		return "", err
+	}
+	inputs, err := cache.HashFiles([]string{definition}, t.Inputs)
+	if err != nil {
+		return "", err
+	}
+	outputs, err := cache.HashFiles(nil, t.Outputs)
+	if err != nil {
+		return "", err
+	}
+	return inputs + ":" + outputs, nil
+}
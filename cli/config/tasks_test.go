@@ -0,0 +1,213 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gtithub.com/jgfranco17/opsrunner/cli/cache"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTaskProject() *ProjectDefinition {
+	return &ProjectDefinition{
+		Name: "tasks",
+		Tasks: map[string]Task{
+			"lint": {
+				Description: "Run linters",
+				Operation:   Operation{Steps: []Step{{Run: "go vet ./..."}}},
+			},
+			"test": {
+				Operation: Operation{
+					FailFast: true,
+					Steps:    []Step{{Run: "go test ./..."}, {Run: "echo done"}},
+				},
+			},
+		},
+	}
+}
+
+func TestTaskNamesSorted(t *testing.T) {
+	assert.Equal(t, []string{"lint", "test"}, newTaskProject().TaskNames())
+}
+
+func TestRunTaskOk(t *testing.T) {
+	exec := &fakeExecutor{}
+	err := RunTask(context.Background(), exec, newTaskProject(), "test", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"go test ./...", "echo done"}, exec.commands)
+}
+
+func TestRunTaskFail_StepFailure(t *testing.T) {
+	exec := &fakeExecutor{exitCodes: map[string]int{"go test ./...": 1}}
+	err := RunTask(context.Background(), exec, newTaskProject(), "test", nil)
+	assert.ErrorContains(t, err, "failed to run task 'test'")
+	assert.Equal(t, []string{"go test ./..."}, exec.commands)
+}
+
+func TestRunTaskFail_UnknownTask(t *testing.T) {
+	exec := &fakeExecutor{}
+	err := RunTask(context.Background(), exec, newTaskProject(), "deploy", nil)
+	assert.ErrorContains(t, err, "task 'deploy' not found (available: lint, test)")
+	assert.Empty(t, exec.commands)
+}
+
+func TestRunTaskRunsDependencies(t *testing.T) {
+	project := newTaskProject()
+	project.Codebase.Build.Steps = []Step{{Run: "go build ./..."}}
+	project.Tasks["release"] = Task{
+		DependsOn: []string{"lint", "test", "build"},
+		Operation: Operation{Steps: []Step{{Run: "goreleaser"}}},
+	}
+	exec := &fakeExecutor{}
+	err := RunTask(context.Background(), exec, project, "release", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"go vet ./...", "go test ./...", "echo done", "go build ./...", "goreleaser"}, exec.commands)
+}
+
+func TestRunTaskFail_ReservedName(t *testing.T) {
+	project := newTaskProject()
+	project.Tasks["build"] = Task{}
+	err := RunTask(context.Background(), &fakeExecutor{}, project, "lint", nil)
+	assert.ErrorContains(t, err, "task name 'build' is reserved")
+}
+
+func TestRunTaskSkipsUpToDateTask(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "schema.graphql")
+	output := filepath.Join(dir, "schema.go")
+	require.NoError(t, os.WriteFile(input, []byte("type Query"), 0o644))
+	project := &ProjectDefinition{
+		Tasks: map[string]Task{
+			"generate": {
+				Inputs:    []string{input},
+				Outputs:   []string{output},
+				Operation: Operation{Steps: []Step{{Run: "go generate"}}},
+			},
+		},
+	}
+	ctx := cache.AddToContext(context.Background(), cache.NewStore(filepath.Join(dir, cache.DefaultDir)))
+	run := func(opts *BuildOptions) []string {
+		exec := &fakeExecutor{}
+		require.NoError(t, RunTask(ctx, exec, project, "generate", opts))
+		return exec.commands
+	}
+
+	// The output is missing until the task created it.
+	assert.Len(t, run(nil), 1)
+	require.NoError(t, os.WriteFile(output, []byte("package schema"), 0o644))
+	assert.Len(t, run(nil), 1)
+	assert.Empty(t, run(nil))
+	assert.Len(t, run(&BuildOptions{Force: true}), 1)
+
+	require.NoError(t, os.WriteFile(input, []byte("type Query { id: ID }"), 0o644))
+	assert.Len(t, run(nil), 1)
+	assert.Empty(t, run(nil))
+
+	require.NoError(t, os.Remove(output))
+	assert.Len(t, run(nil), 1)
+
+	task := project.Tasks["generate"]
+	task.Env = map[string]string{"GOFLAGS": "-mod=vendor"}
+	project.Tasks["generate"] = task
+	assert.Len(t, run(nil), 1)
+}
+
+func TestRunTaskReRunsChangedDefinition(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "main.go")
+	require.NoError(t, os.WriteFile(input, []byte("package main"), 0o644))
+	project := &ProjectDefinition{
+		Tasks: map[string]Task{
+			"check": {
+				Inputs: []string{input},
+				Operation: Operation{Steps: []Step{
+					{Parallel: []Step{{Run: "go vet ./..."}, {Run: "go test ./..."}}},
+				}},
+			},
+		},
+	}
+	ctx := cache.AddToContext(context.Background(), cache.NewStore(filepath.Join(dir, cache.DefaultDir)))
+	run := func() []string {
+		exec := &fakeExecutor{}
+		require.NoError(t, RunTask(ctx, exec, project, "check", nil))
+		return exec.commands
+	}
+	update := func(change func(op *Operation)) {
+		task := project.Tasks["check"]
+		change(&task.Operation)
+		project.Tasks["check"] = task
+	}
+
+	assert.Len(t, run(), 2)
+	assert.Empty(t, run())
+
+	update(func(op *Operation) {
+		op.Steps = []Step{{Parallel: []Step{{Run: "go vet ./..."}, {Run: "go test -race ./..."}}}}
+	})
+	assert.Len(t, run(), 2)
+	assert.Empty(t, run())
+
+	update(func(op *Operation) { op.Steps[0].Parallel[0].If = `os == "linux"` })
+	assert.NotEmpty(t, run())
+	update(func(op *Operation) { op.Shell = &Shell{Command: "bash", Strict: true} })
+	assert.NotEmpty(t, run())
+	update(func(op *Operation) { op.Matrix = &Matrix{Axes: map[string][]string{"go": {"1.23"}}} })
+	assert.NotEmpty(t, run())
+	update(func(op *Operation) { op.EnvMode = EnvModeClean })
+	assert.NotEmpty(t, run())
+	update(func(op *Operation) {
+		op.EnvMode = EnvModeAllowlist
+		op.EnvAllowlist = []string{"HOME"}
+	})
+	assert.NotEmpty(t, run())
+	update(func(op *Operation) { op.EnvAllowlist = []string{"HOME", "GOPATH"} })
+	assert.NotEmpty(t, run())
+	update(func(op *Operation) { op.Requires = []string{"go"} })
+	assert.NotEmpty(t, run())
+	update(func(op *Operation) { op.Hooks = &Hooks{After: &Operation{Steps: []Step{{Run: "echo done"}}}} })
+	assert.NotEmpty(t, run())
+	assert.Empty(t, run())
+}
+
+func TestTaskFingerprintIncludesSession(t *testing.T) {
+	task := Task{Operation: Operation{Steps: []Step{{Run: "cd docs"}, {Run: "make"}}}}
+	before, err := task.fingerprint()
+	require.NoError(t, err)
+	task.Session = true
+	after, err := task.fingerprint()
+	require.NoError(t, err)
+	assert.NotEqual(t, before, after)
+}
+
+func TestRunTaskDoesNotRecordSkippedTask(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "schema.graphql")
+	require.NoError(t, os.WriteFile(input, []byte("type Query"), 0o644))
+	project := &ProjectDefinition{
+		Tasks: map[string]Task{
+			"gen": {
+				Inputs:    []string{input},
+				Operation: Operation{If: `env.DO_GEN == "yes"`, Steps: []Step{{Run: "go generate"}}},
+			},
+		},
+	}
+	store := cache.NewStore(filepath.Join(dir, cache.DefaultDir))
+	ctx := cache.AddToContext(context.Background(), store)
+
+	t.Setenv("DO_GEN", "no")
+	exec := &fakeExecutor{}
+	require.NoError(t, RunTask(ctx, exec, project, "gen", nil))
+	assert.Empty(t, exec.commands)
+	_, ok, err := store.Get(taskCacheKey("gen"))
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	t.Setenv("DO_GEN", "yes")
+	exec = &fakeExecutor{}
+	require.NoError(t, RunTask(ctx, exec, project, "gen", nil))
+	assert.Equal(t, []string{"go generate"}, exec.commands)
+}
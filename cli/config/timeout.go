@@ -0,0 +1,75 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	timeoutScopeStep      = "step"
+	timeoutScopeOperation = "operation"
+	timeoutScopeRun       = "run"
+)
+
+// TimeoutError is returned when a step is stopped because a timeout
+// expired while it was running.
+type TimeoutError struct {
+	// Step is the label of the step that was stopped.
+	Step string
+	// Scope is the level the expired timeout was set at: step,
+	// operation or run.
+	Scope string
+	// Limit is the duration of the expired timeout.
+	Limit time.Duration
+	// Elapsed is how long the step ran before it was stopped.
+	Elapsed time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("step '%s' timed out after %s (%s timeout of %s)",
+		e.Step, e.Elapsed.Round(time.Millisecond), e.Scope, e.Limit)
+}
+
+// deadline is the cancellation cause of contexts created by withTimeout.
+type deadline struct {
+	scope string
+	limit time.Duration
+}
+
+func (d *deadline) Error() string {
+	return fmt.Sprintf("%s timeout of %s expired", d.scope, d.limit)
+}
+
+func (d *deadline) Is(target error) bool {
+	return target == context.DeadlineExceeded
+}
+
+// WithRunTimeout returns a context that stops every remaining step once
+// the limit expires. A zero limit disables the timeout.
+func WithRunTimeout(ctx context.Context, limit time.Duration) (context.Context, context.CancelFunc) {
+	return withTimeout(ctx, limit, timeoutScopeRun)
+}
+
+func withTimeout(ctx context.Context, limit time.Duration, scope string) (context.Context, context.CancelFunc) {
+	if limit <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeoutCause(ctx, limit, &deadline{scope: scope, limit: limit})
+}
+
+// asTimeout converts the cancellation of a step into a TimeoutError if
+// it was caused by an expired timeout.
+func asTimeout(ctx context.Context, step string, elapsed time.Duration) (*TimeoutError, bool) {
+	var expired *deadline
+	if !errors.As(context.Cause(ctx), &expired) {
+		return nil, false
+	}
+	return &TimeoutError{
+		Step:    step,
+		Scope:   expired.scope,
+		Limit:   expired.limit,
+		Elapsed: elapsed,
+	}, true
+}
@@ -0,0 +1,59 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gtithub.com/jgfranco17/opsrunner/cli/executor"
+)
+
+func TestOperationRunStepTimeout(t *testing.T) {
+	op := Operation{
+		Steps: []Step{
+			{Name: "hang", Run: "sleep 5", Timeout: 100 * time.Millisecond},
+			{Run: "true"},
+		},
+	}
+	err := op.Run(context.Background(), &executor.DefaultExecutor{})
+
+	var timeoutErr *TimeoutError
+	require.True(t, errors.As(err, &timeoutErr), "expected a timeout error, got %v", err)
+	assert.Equal(t, "hang", timeoutErr.Step)
+	assert.Equal(t, timeoutScopeStep, timeoutErr.Scope)
+	assert.Equal(t, 100*time.Millisecond, timeoutErr.Limit)
+	assert.GreaterOrEqual(t, timeoutErr.Elapsed, 100*time.Millisecond)
+	assert.ErrorContains(t, err, "failed to run steps: [hang]")
+}
+
+func TestOperationRunOperationTimeoutStopsRemainingSteps(t *testing.T) {
+	op := Operation{
+		Timeout: 200 * time.Millisecond,
+		Steps:   []Step{{Run: "sleep 5"}, {Run: "echo unreachable"}},
+	}
+	start := time.Now()
+	err := op.Run(context.Background(), &executor.DefaultExecutor{})
+	assert.Less(t, time.Since(start), 3*time.Second)
+
+	var timeoutErr *TimeoutError
+	require.True(t, errors.As(err, &timeoutErr), "expected a timeout error, got %v", err)
+	assert.Equal(t, "sleep 5", timeoutErr.Step)
+	assert.Equal(t, timeoutScopeOperation, timeoutErr.Scope)
+}
+
+func TestWithRunTimeout(t *testing.T) {
+	ctx, cancel := WithRunTimeout(context.Background(), 0)
+	defer cancel()
+	_, hasDeadline := ctx.Deadline()
+	assert.False(t, hasDeadline)
+
+	ctx, cancel = WithRunTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	<-ctx.Done()
+	assert.ErrorIs(t, context.Cause(ctx), context.DeadlineExceeded)
+	assert.EqualError(t, context.Cause(ctx), "run timeout of 1ms expired")
+}
@@ -0,0 +1,443 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// semverPattern matches a semantic version as defined by https://semver.org.
+var semverPattern = regexp.MustCompile(`^(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)` +
+	`(?:-((?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*)(?:\.(?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*))*))?` +
+	`(?:\+([0-9a-zA-Z-]+(?:\.[0-9a-zA-Z-]+)*))?$`)
+
+// Diagnostic describes a single problem found in a project definition.
+// Line and Column are 1-based and zero when the position is unknown. File
+// is only set for problems in included files.
+type Diagnostic struct {
+	File    string
+	Line    int
+	Column  int
+	Path    string
+	Message string
+}
+
+func (d Diagnostic) String() string {
+	var location string
+	if d.Line > 0 {
+		location = fmt.Sprintf("%d:%d: ", d.Line, d.Column)
+	}
+	if d.File != "" {
+		location = d.File + ":" + location
+	}
+	if d.Path == "" {
+		return location + d.Message
+	}
+	return fmt.Sprintf("%s%s: %s", location, d.Path, d.Message)
+}
+
+// ValidationError collects every diagnostic reported by Validate.
+type ValidationError struct {
+	Diagnostics []Diagnostic
+}
+
+func (e *ValidationError) Error() string {
+	lines := make([]string, 0, len(e.Diagnostics))
+	for _, diag := range e.Diagnostics {
+		lines = append(lines, diag.String())
+	}
+	return fmt.Sprintf("configuration is invalid (%d problem(s)):\n  %s", len(e.Diagnostics), strings.Join(lines, "\n  "))
+}
+
+// Validate checks the project definition for missing required fields,
+// malformed values, broken task dependencies and, when the definition was
+// read with Load, unknown keys. All problems are reported together in a
+// ValidationError.
+func (p *ProjectDefinition) Validate() error {
+	var diags []Diagnostic
+	report := func(message string, path ...string) {
+		file, line, column := p.position(path...)
+		diags = append(diags, Diagnostic{
+			File:    file,
+			Line:    line,
+			Column:  column,
+			Path:    strings.Join(path, "."),
+			Message: message,
+		})
+	}
+
+	if p.source != nil {
+		diags = append(diags, p.checkKnownFields(p.source, reflect.TypeOf(p).Elem(), "")...)
+	}
+	if strings.TrimSpace(p.Name) == "" {
+		report("required field 'name' is missing")
+	}
+	if p.Version == "" {
+		report("required field 'version' is missing")
+	} else if !semverPattern.MatchString(p.Version) {
+		report(fmt.Sprintf("'%s' is not a semantic version (e.g. 1.2.3)", p.Version), "version")
+	}
+	if p.Codebase.Language != "" && !slices.Contains(SupportedLanguages, p.Codebase.Language) {
+		report(fmt.Sprintf("unsupported language '%s' (expected one of: %s)", p.Codebase.Language,
+			strings.Join(p.Codebase.Language.enumValues(), ", ")), "codebase", "language")
+	}
+	if len(p.Codebase.Build.Steps) == 0 {
+		report("at least one build step is required", "codebase", "build")
+	}
+	if p.Shell != nil {
+		if err := p.Shell.check(false); err != nil {
+			report(err.Error(), "shell")
+		}
+	}
+	checkRetry := func(retry *RetryPolicy, path ...string) {
+		if retry == nil {
+			return
+		}
+		if retry.Attempts < 1 {
+			report("retry attempts must be at least 1", path...)
+		}
+		if retry.Delay < 0 {
+			report("retry delay cannot be negative", path...)
+		}
+		if retry.Backoff != 0 && retry.Backoff < 1 {
+			report("retry backoff must be at least 1", path...)
+		}
+	}
+	var checkOperation func(op *Operation, path ...string)
+	checkHooks := func(hooks *Hooks, path ...string) {
+		for _, hook := range hooks.hooks() {
+			hookPath := append(slices.Clone(path), "hooks", hook.Name)
+			if len(hook.Operation.Steps) == 0 {
+				report("hook must define steps", hookPath...)
+			}
+			checkOperation(hook.Operation, hookPath...)
+		}
+	}
+	checkOperation = func(op *Operation, path ...string) {
+		if op.If != "" {
+			if _, err := parseExpr(op.If); err != nil {
+				report(err.Error(), append(path, "if")...)
+			} else if usesStatus(op.If) {
+				report(errOperationStatus.Error(), append(path, "if")...)
+			}
+		}
+		// earlier holds the names of the steps whose outputs are available.
+		var earlier []string
+		var checkStep func(step Step, name string, nested bool)
+		checkStep = func(step Step, name string, nested bool) {
+			stepsPath := append(slices.Clone(path), "steps")
+			if step.If != "" {
+				if _, err := parseExpr(step.If); err != nil {
+					report(fmt.Sprintf("%s: %s", name, err), stepsPath...)
+				}
+			}
+			fields := append([]string{step.If, step.Run, step.Dir}, slices.Sorted(maps.Values(step.Env))...)
+			for _, reference := range stepReferences(strings.Join(fields, "\n")) {
+				if stepName, _, ok := outputReference(reference); !ok {
+					report(fmt.Sprintf("%s: invalid reference 'steps.%s' (expected steps.<name>.outputs.<key>)", name, reference), stepsPath...)
+				} else if !slices.Contains(earlier, stepName) {
+					report(fmt.Sprintf("%s refers to the outputs of '%s', which is not an earlier step", name, stepName), stepsPath...)
+				}
+			}
+			if step.Shell != nil {
+				if err := step.Shell.check(op.Session); err != nil {
+					report(fmt.Sprintf("%s: %s", name, err), stepsPath...)
+				}
+			}
+			checkRetry(step.Retry, stepsPath...)
+			if len(step.Parallel) == 0 {
+				if strings.TrimSpace(step.Run) == "" {
+					report(fmt.Sprintf("%s has an empty command", name), stepsPath...)
+				}
+				return
+			}
+			switch {
+			case nested:
+				report(fmt.Sprintf("%s: parallel groups cannot be nested", name), stepsPath...)
+			case step.Run != "":
+				report(fmt.Sprintf("%s cannot define both run and parallel", name), stepsPath...)
+			case step.MaxConcurrency < 0:
+				report(fmt.Sprintf("%s: max_concurrency cannot be negative", name), stepsPath...)
+			case op.Session:
+				report(fmt.Sprintf("%s: parallel groups cannot run in a shell session", name), stepsPath...)
+			}
+			for idx, child := range step.Parallel {
+				checkStep(child, fmt.Sprintf("%s.%d", name, idx+1), true)
+			}
+		}
+		for idx, step := range op.Steps {
+			checkStep(step, fmt.Sprintf("step %d", idx+1), false)
+			earlier = append(earlier, stepNames([]Step{step})...)
+		}
+		// The project shell is checked once, unless it must run a session.
+		if op.Shell != nil && (op.Shell != p.Shell || op.Session) {
+			if err := op.Shell.check(op.Session); err != nil {
+				report(err.Error(), append(path, "shell")...)
+			}
+		}
+		checkRetry(op.Retry, append(path, "retry")...)
+		if op.EnvMode != "" && !slices.Contains(op.EnvMode.enumValues(), string(op.EnvMode)) {
+			report(fmt.Sprintf("unknown env_mode '%s' (expected one of: %s)", op.EnvMode,
+				strings.Join(op.EnvMode.enumValues(), ", ")), append(path, "env_mode")...)
+		}
+		if len(op.EnvAllowlist) > 0 && op.EnvMode != EnvModeAllowlist {
+			report("env_allowlist is only used with env_mode 'allowlist'", append(path, "env_allowlist")...)
+		}
+		if op.Matrix != nil {
+			checkMatrix(op, func(message string) {
+				report(message, append(path, "matrix")...)
+			})
+		}
+		checkHooks(op.Hooks, path...)
+	}
+	checkHooks(p.Hooks)
+	checkOperation(&p.Codebase.Install, "codebase", "install")
+	checkOperation(&p.Codebase.Build, "codebase", "build")
+	for _, name := range p.TaskNames() {
+		task := p.Tasks[name]
+		if len(task.Steps) == 0 && len(task.DependsOn) == 0 {
+			report("task must define steps or depends_on", "tasks", name)
+		}
+		checkOperation(&task.Operation, "tasks", name)
+	}
+
+	if graph, err := NewProjectGraph(nil, p, nil); err != nil {
+		report(err.Error(), "tasks")
+	} else {
+		for _, name := range p.TaskNames() {
+			for _, dep := range p.Tasks[name].DependsOn {
+				if _, ok := graph.nodes[dep]; !ok {
+					report(fmt.Sprintf("depends on undefined task '%s'", dep), "tasks", name, "depends_on")
+				}
+			}
+			var cycleErr *CycleError
+			if _, err := graph.Resolve(name); errors.As(err, &cycleErr) {
+				// Report each cycle once, against its alphabetically first task.
+				if slices.Min(cycleErr.Path) == name {
+					report(err.Error(), "tasks", name, "depends_on")
+				}
+			}
+		}
+	}
+
+	if len(diags) == 0 {
+		return nil
+	}
+	sort.SliceStable(diags, func(i, j int) bool {
+		if diags[i].File != diags[j].File {
+			return diags[i].File < diags[j].File
+		}
+		if diags[i].Line != diags[j].Line {
+			return diags[i].Line < diags[j].Line
+		}
+		return diags[i].Column < diags[j].Column
+	})
+	return &ValidationError{Diagnostics: diags}
+}
+
+// position returns the file and location of the key at the given path in
+// the source document, falling back to the closest known parent.
+func (p *ProjectDefinition) position(path ...string) (string, int, int) {
+	node := documentRoot(p.source)
+	if node == nil {
+		return "", 0, 0
+	}
+	file, line, column := p.origins[node], node.Line, node.Column
+	for _, key := range path {
+		if node.Kind != yaml.MappingNode {
+			break
+		}
+		var next *yaml.Node
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == key {
+				file, line, column = p.origins[node.Content[i]], node.Content[i].Line, node.Content[i].Column
+				next = node.Content[i+1]
+				break
+			}
+		}
+		if next == nil {
+			break
+		}
+		node = next
+	}
+	return file, line, column
+}
+
+func documentRoot(node *yaml.Node) *yaml.Node {
+	if node != nil && node.Kind == yaml.DocumentNode {
+		if len(node.Content) == 0 {
+			return nil
+		}
+		return node.Content[0]
+	}
+	return node
+}
+
+// checkKnownFields walks the YAML node alongside the Go type it decodes
+// into and reports every mapping key that has no matching field.
+func (p *ProjectDefinition) checkKnownFields(node *yaml.Node, t reflect.Type, path string) []Diagnostic {
+	node = documentRoot(node)
+	if node == nil {
+		return nil
+	}
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	var diags []Diagnostic
+	switch t.Kind() {
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			return nil
+		}
+		fields := yamlFields(t)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			fieldType, ok := fields[key.Value]
+			if !ok && inlineMap(t) != nil {
+				fieldType, ok = inlineMap(t).Elem(), true
+			}
+			if !ok {
+				message := fmt.Sprintf("unknown field '%s'", key.Value)
+				if suggestion := suggest(key.Value, fields); suggestion != "" {
+					message += fmt.Sprintf(" (did you mean '%s'?)", suggestion)
+				}
+				diags = append(diags, Diagnostic{
+					File:    p.origins[key],
+					Line:    key.Line,
+					Column:  key.Column,
+					Path:    path,
+					Message: message,
+				})
+				continue
+			}
+			diags = append(diags, p.checkKnownFields(value, fieldType, joinPath(path, key.Value))...)
+		}
+	case reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			return nil
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			diags = append(diags, p.checkKnownFields(node.Content[i+1], t.Elem(), joinPath(path, node.Content[i].Value))...)
+		}
+	case reflect.Slice:
+		if node.Kind != yaml.SequenceNode {
+			return nil
+		}
+		for idx, item := range node.Content {
+			diags = append(diags, p.checkKnownFields(item, t.Elem(), fmt.Sprintf("%s[%d]", path, idx))...)
+		}
+	}
+	return diags
+}
+
+// yamlField is a struct field together with its YAML key.
+type yamlField struct {
+	Key string
+	reflect.StructField
+}
+
+// yamlFieldList returns the fields of the struct type in declaration
+// order, expanding those of inlined structs.
+func yamlFieldList(t reflect.Type) []yamlField {
+	var fields []yamlField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		tag := field.Tag.Get("yaml")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if strings.Contains(opts, "inline") {
+			if field.Type.Kind() == reflect.Struct {
+				fields = append(fields, yamlFieldList(field.Type)...)
+			}
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		fields = append(fields, yamlField{Key: name, StructField: field})
+	}
+	return fields
+}
+
+// inlineMap returns the type of the inlined map of the struct type, which
+// holds every key without a field of its own, or nil if there is none.
+func inlineMap(t reflect.Type) reflect.Type {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		_, opts, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if strings.Contains(opts, "inline") && field.Type.Kind() == reflect.Map {
+			return field.Type
+		}
+	}
+	return nil
+}
+
+// yamlFields maps the YAML key of every field in the struct type to the
+// field's type.
+func yamlFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for _, field := range yamlFieldList(t) {
+		fields[field.Key] = field.Type
+	}
+	return fields
+}
+
+func joinPath(parent string, key string) string {
+	if parent == "" {
+		return key
+	}
+	return parent + "." + key
+}
+
+// suggest returns the known key closest to the unknown one, or an empty
+// string if none is similar enough.
+func suggest(unknown string, known map[string]reflect.Type) string {
+	normalized := strings.ReplaceAll(strings.ToLower(unknown), "-", "_")
+	best, bestDistance := "", 3
+	for candidate := range known {
+		if candidate == normalized {
+			return candidate
+		}
+		distance := levenshtein(normalized, candidate)
+		if distance < bestDistance || (distance == bestDistance && candidate < best) {
+			best, bestDistance = candidate, distance
+		}
+	}
+	return best
+}
+
+func levenshtein(a string, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
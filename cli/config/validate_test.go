@@ -0,0 +1,188 @@
+package config
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func validationDiagnostics(t *testing.T, content string) []string {
+	t.Helper()
+	cfg, err := Load(strings.NewReader(content))
+	require.NoError(t, err)
+	err = cfg.Validate()
+	var validationErr *ValidationError
+	require.True(t, errors.As(err, &validationErr), "expected a validation error, got %v", err)
+	var diags []string
+	for _, diag := range validationErr.Diagnostics {
+		diags = append(diags, diag.String())
+	}
+	return diags
+}
+
+func TestValidateOk(t *testing.T) {
+	cfg, err := Load(strings.NewReader(`---
+name: demo
+version: 1.2.3-rc.1+build.5
+codebase:
+  build:
+    steps:
+      - go build ./...
+tasks:
+  release:
+    depends_on: [build]
+`))
+	require.NoError(t, err)
+	assert.NoError(t, cfg.Validate())
+}
+
+func TestValidateFail_RequiredFields(t *testing.T) {
+	diags := validationDiagnostics(t, `---
+description: nothing here
+codebase:
+  build:
+    steps: []
+`)
+	assert.Equal(t, []string{
+		"2:1: required field 'name' is missing",
+		"2:1: required field 'version' is missing",
+		"4:3: codebase.build: at least one build step is required",
+	}, diags)
+}
+
+func TestValidateFail_InvalidVersion(t *testing.T) {
+	diags := validationDiagnostics(t, `---
+name: demo
+version: v1.0
+codebase:
+  build:
+    steps: [make]
+`)
+	assert.Equal(t, []string{"3:1: version: 'v1.0' is not a semantic version (e.g. 1.2.3)"}, diags)
+}
+
+func TestValidateFail_UnknownKeysWithSuggestions(t *testing.T) {
+	diags := validationDiagnostics(t, `---
+name: demo
+version: 1.0.0
+codebase:
+  build:
+    fail-fast: true
+    steps:
+      - name: compile
+        run: make
+        timout: 1m
+tasks:
+  lint:
+    descripton: Run linters
+    steps: [make lint]
+    bogus: true
+`)
+	assert.Equal(t, []string{
+		"6:5: codebase.build: unknown field 'fail-fast' (did you mean 'fail_fast'?)",
+		"10:9: codebase.build.steps[0]: unknown field 'timout' (did you mean 'timeout'?)",
+		"13:5: tasks.lint: unknown field 'descripton' (did you mean 'description'?)",
+		"15:5: tasks.lint: unknown field 'bogus'",
+	}, diags)
+}
+
+func TestValidateFail_TaskDependencies(t *testing.T) {
+	diags := validationDiagnostics(t, `---
+name: demo
+version: 1.0.0
+codebase:
+  build:
+    steps: [make]
+tasks:
+  a:
+    depends_on: [b]
+  b:
+    depends_on: [a, missing]
+  empty: {}
+`)
+	assert.Equal(t, []string{
+		"9:5: tasks.a.depends_on: dependency cycle detected: a -> b -> a",
+		"11:5: tasks.b.depends_on: depends on undefined task 'missing'",
+		"12:3: tasks.empty: task must define steps or depends_on",
+	}, diags)
+}
+
+func TestValidateFail_UnsupportedLanguage(t *testing.T) {
+	diags := validationDiagnostics(t, `---
+name: demo
+version: 1.0.0
+codebase:
+  language: cobol
+  build:
+    steps: [make]
+`)
+	assert.Equal(t, []string{"5:3: codebase.language: unsupported language 'cobol' (expected one of: go, python, node, rust, java)"}, diags)
+}
+
+func TestValidateFail_EnvMode(t *testing.T) {
+	diags := validationDiagnostics(t, `---
+name: demo
+version: 1.0.0
+codebase:
+  build:
+    env_mode: sandbox
+    env_allowlist: [GOPATH]
+    steps: [make]
+`)
+	assert.Equal(t, []string{
+		"6:5: codebase.build.env_mode: unknown env_mode 'sandbox' (expected one of: inherit, clean, allowlist)",
+		"7:5: codebase.build.env_allowlist: env_allowlist is only used with env_mode 'allowlist'",
+	}, diags)
+}
+
+func TestValidateFail_InvalidCondition(t *testing.T) {
+	diags := validationDiagnostics(t, `---
+name: demo
+version: 1.0.0
+codebase:
+  build:
+    if: os = "linux"
+    steps:
+      - run: make
+        if: colour == "red"
+`)
+	assert.Equal(t, []string{
+		"6:5: codebase.build.if: invalid condition 'os = \"linux\"': unexpected character '=' at position 4",
+		"7:5: codebase.build.steps: step 1: invalid condition 'colour == \"red\"': unknown variable 'colour' at position 1",
+	}, diags)
+}
+
+func TestValidateFail_OperationStatusCondition(t *testing.T) {
+	diags := validationDiagnostics(t, `---
+name: demo
+version: 1.0.0
+codebase:
+  build:
+    if: failure()
+    steps:
+      - run: make clean
+        if: failure()
+`)
+	assert.Equal(t, []string{
+		"6:5: codebase.build.if: success(), failure() and always() only apply to steps; " +
+			"use hooks.on_failure or hooks.always to run steps after a failure",
+	}, diags)
+}
+
+func TestValidateFail_EmptyHook(t *testing.T) {
+	diags := validationDiagnostics(t, `---
+name: demo
+version: 1.0.0
+codebase:
+  build:
+    steps: [make]
+hooks:
+  always:
+    env:
+      KEEP: "1"
+`)
+	assert.Equal(t, []string{"8:3: hooks.always: hook must define steps"}, diags)
+}
@@ -2,45 +2,49 @@ package core
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 
+	"gtithub.com/jgfranco17/opsrunner/cli/cache"
 	"gtithub.com/jgfranco17/opsrunner/cli/config"
 	"gtithub.com/jgfranco17/opsrunner/cli/executor"
 	"gtithub.com/jgfranco17/opsrunner/cli/logging"
+	"gtithub.com/jgfranco17/opsrunner/cli/outputs"
 )
 
 type BashExecutor interface {
-	Exec(ctx context.Context, command string) (executor.Result, error)
-	AddEnv(env []string)
+	Exec(ctx context.Context, command executor.Command) (executor.Result, error)
 }
 
 func GetBuildCommand(shellExecutor BashExecutor) *cobra.Command {
-	var filePath string
+	var configFlags configFlags
 	var noInstall bool
+	var forceInstall bool
+	var flags runFlags
 	cmd := &cobra.Command{
 		Use:   "build",
 		Short: "Run the build operations",
 		Long:  "Read the config file and run the build operations defined in it.",
 		Args:  cobra.ExactArgs(0),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			logger := logging.FromContext(cmd.Context())
-			ctx, cancel := context.WithCancel(cmd.Context())
+			ctx, cancel := flags.setup(cmd.Context())
 			defer cancel()
-			logger.Debugf("Starting build with config file: %s", filePath)
-			contents, err := os.Open(filePath)
-			if err != nil {
-				return fmt.Errorf("failed to open file %s: %w", filePath, err)
-			}
-			cfg, err := config.Load(contents)
+			cfg, err := configFlags.load(cmd.Context(), true)
 			if err != nil {
-				return fmt.Errorf("failed to load config from file: %w", err)
+				return err
 			}
 			opts := &config.BuildOptions{
-				NoInstall: noInstall,
+				NoInstall:    noInstall,
+				ForceInstall: forceInstall,
 			}
+			ctx = cache.AddToContext(ctx, configFlags.store())
 			if err := config.Build(ctx, shellExecutor, cfg, opts); err != nil {
 				return fmt.Errorf("build failed: %w", err)
 			}
@@ -49,7 +53,262 @@ func GetBuildCommand(shellExecutor BashExecutor) *cobra.Command {
 		SilenceUsage:  true,
 		SilenceErrors: true,
 	}
-	cmd.Flags().StringVarP(&filePath, "file", "f", ".opsrunner.yaml", "OpsRunner definition file")
+	configFlags.register(cmd, true)
 	cmd.Flags().BoolVar(&noInstall, "no-install", false, "Install codebase dependencies before building")
+	cmd.Flags().BoolVar(&forceInstall, "force-install", false, "Install codebase dependencies even if they did not change since the last install")
+	flags.register(cmd)
+	return cmd
+}
+
+func GetRunCommand(shellExecutor BashExecutor) *cobra.Command {
+	var configFlags configFlags
+	var force bool
+	var flags runFlags
+	cmd := &cobra.Command{
+		Use:   "run [task]",
+		Short: "Run a named task",
+		Long:  "Read the config file and run one of the tasks defined in it. Lists the available tasks if none is given.",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := flags.setup(cmd.Context())
+			defer cancel()
+			cfg, err := configFlags.load(cmd.Context(), true)
+			if err != nil {
+				return err
+			}
+			if len(args) == 0 {
+				printTaskList(cmd.OutOrStdout(), cfg)
+				return nil
+			}
+			ctx = cache.AddToContext(ctx, configFlags.store())
+			opts := &config.BuildOptions{
+				Force: force,
+			}
+			if err := config.RunTask(ctx, shellExecutor, cfg, args[0], opts); err != nil {
+				return fmt.Errorf("run failed: %w", err)
+			}
+			return nil
+		},
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+	configFlags.register(cmd, true)
+	cmd.Flags().BoolVar(&force, "force", false, "Run tasks even if their inputs and outputs did not change since their last run")
+	flags.register(cmd)
 	return cmd
 }
+
+func GetValidateCommand() *cobra.Command {
+	var configFlags configFlags
+	cmd := &cobra.Command{
+		Use:   "validate",
+		Short: "Validate the definition file",
+		Long:  "Check the config file for missing fields, invalid values and unknown keys without running anything.",
+		Args:  cobra.ExactArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := configFlags.load(cmd.Context(), false)
+			if err != nil {
+				return err
+			}
+			filePath := configFlags.file
+			err = cfg.Validate()
+			var validationErr *config.ValidationError
+			if errors.As(err, &validationErr) {
+				for _, diag := range validationErr.Diagnostics {
+					if diag.File == "" {
+						diag.File = filePath
+					}
+					_, _ = fmt.Fprintln(cmd.ErrOrStderr(), diag.String())
+				}
+				return fmt.Errorf("validation failed: %d problem(s) found in %s", len(validationErr.Diagnostics), filePath)
+			} else if err != nil {
+				return fmt.Errorf("validation failed: %w", err)
+			}
+			_, _ = fmt.Fprintf(cmd.OutOrStdout(), "%s is valid\n", filePath)
+			return nil
+		},
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+	configFlags.register(cmd, false)
+	return cmd
+}
+
+func GetSchemaCommand() *cobra.Command {
+	var outputPath string
+	cmd := &cobra.Command{
+		Use:   "schema",
+		Short: "Print the JSON Schema of the definition file",
+		Long:  "Generate a JSON Schema document for the definition file, for use with editors and other validation tooling.",
+		Args:  cobra.ExactArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			logger := logging.FromContext(cmd.Context())
+			schema, err := config.JSONSchema()
+			if err != nil {
+				return fmt.Errorf("failed to generate schema: %w", err)
+			}
+			if outputPath == "" {
+				_, _ = fmt.Fprintln(cmd.OutOrStdout(), string(schema))
+				return nil
+			}
+			if err := os.WriteFile(outputPath, append(schema, '\n'), 0644); err != nil {
+				return fmt.Errorf("failed to write schema to %s: %w", outputPath, err)
+			}
+			logger.Infof("Schema written to %s", outputPath)
+			return nil
+		},
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+	cmd.Flags().StringVarP(&outputPath, "output", "o", "", "Write the schema to a file instead of stdout")
+	return cmd
+}
+
+func GetCacheCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cache",
+		Short: "Manage the local state of the project",
+		Long:  "Inspect or clear the state OpsRunner keeps to skip unchanged installs and tasks.",
+	}
+	var configFlags configFlags
+	clearCmd := &cobra.Command{
+		Use:   "clear",
+		Short: "Clear the local state",
+		Long:  "Remove the state directory next to the definition file, so that the next runs install dependencies and run every task again.",
+		Args:  cobra.ExactArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			logger := logging.FromContext(cmd.Context())
+			if _, err := configFlags.load(cmd.Context(), false); err != nil {
+				return err
+			}
+			store := configFlags.store()
+			if err := store.Clear(); err != nil {
+				return err
+			}
+			logger.Infof("Cleared %s", store.Dir())
+			return nil
+		},
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+	configFlags.register(clearCmd, false)
+	cmd.AddCommand(clearCmd)
+	return cmd
+}
+
+// runFlags holds the flags shared by the commands that run steps.
+type runFlags struct {
+	prefix      bool
+	timestamps  bool
+	timeout     time.Duration
+	gracePeriod time.Duration
+}
+
+func (f *runFlags) register(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&f.prefix, "prefix", false, "Prefix each line of step output with the step name")
+	cmd.Flags().BoolVar(&f.timestamps, "timestamps", false, "Prefix each line of step output with the time it was written")
+	cmd.Flags().DurationVar(&f.timeout, "timeout", 0, "Maximum run time of all steps combined, e.g. 30m (no limit by default)")
+	cmd.Flags().DurationVar(&f.gracePeriod, "grace-period", executor.DefaultGracePeriod, "Time given to interrupted steps to exit before they are killed")
+}
+
+// setup prepares the context for running steps, applying the flags and
+// handling interrupt signals. The returned function releases its resources.
+func (f *runFlags) setup(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, stopSignals := withSignalHandling(f.addToContext(ctx))
+	ctx, cancelTimeout := config.WithRunTimeout(ctx, f.timeout)
+	return ctx, func() {
+		cancelTimeout()
+		stopSignals()
+	}
+}
+
+func (f *runFlags) addToContext(ctx context.Context) context.Context {
+	stream := outputs.DefaultStream()
+	stream.Prefix = f.prefix
+	stream.Timestamps = f.timestamps
+	ctx = outputs.AddToContext(ctx, stream)
+	return executor.WithGracePeriod(ctx, f.gracePeriod)
+}
+
+// configFlags holds the flags selecting and loading the definition file.
+type configFlags struct {
+	file    string
+	profile string
+	noChdir bool
+
+	// dir is the absolute directory of the loaded definition file.
+	dir string
+}
+
+// register adds the flags to the command. The chdir flag is only offered
+// by commands that run steps.
+func (f *configFlags) register(cmd *cobra.Command, chdir bool) {
+	cmd.Flags().StringVarP(&f.file, "file", "f", "", "OpsRunner definition file (searched for in the current directory and its parents by default)")
+	cmd.Flags().StringVar(&f.profile, "profile", "", "Profile to apply (defaults to $"+config.ProfileEnvVar+", or 'ci' when running in CI)")
+	if chdir {
+		cmd.Flags().BoolVar(&f.noChdir, "no-chdir", false, "Run steps in the current directory instead of the one of the definition file")
+	}
+}
+
+// load locates and loads the definition file. When chdir is set and not
+// disabled by the flag, the working directory is changed to the one of
+// the definition file so that steps run relative to it.
+func (f *configFlags) load(ctx context.Context, chdir bool) (*config.ProjectDefinition, error) {
+	logger := logging.FromContext(ctx)
+	if f.file == "" {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get working directory: %w", err)
+		}
+		path, err := config.Discover(cwd)
+		if err != nil {
+			return nil, err
+		}
+		if rel, err := filepath.Rel(cwd, path); err == nil {
+			path = rel
+		}
+		f.file = path
+	}
+	logger.Debugf("Using definition file %s", f.file)
+	cfg, err := config.LoadFile(f.file, &config.LoadOptions{Profile: f.profile})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config from file: %w", err)
+	}
+	if cfg.ActiveProfile != "" {
+		logger.Infof("Using profile '%s'", cfg.ActiveProfile)
+	}
+	dir, err := filepath.Abs(filepath.Dir(f.file))
+	if err != nil {
+		return nil, err
+	}
+	f.dir = dir
+	if dir := filepath.Dir(f.file); chdir && !f.noChdir && dir != "." {
+		if err := os.Chdir(dir); err != nil {
+			return nil, fmt.Errorf("failed to change to directory %s: %w", dir, err)
+		}
+		logger.Debugf("Running steps in %s", dir)
+	}
+	return cfg, nil
+}
+
+// store returns the cache kept next to the loaded definition file.
+func (f *configFlags) store() *cache.Store {
+	return cache.NewStore(filepath.Join(f.dir, cache.DefaultDir))
+}
+
+func printTaskList(w io.Writer, cfg *config.ProjectDefinition) {
+	names := cfg.TaskNames()
+	if len(names) == 0 {
+		_, _ = fmt.Fprintln(w, "No tasks defined.")
+		return
+	}
+	_, _ = fmt.Fprintln(w, "Available tasks:")
+	for _, name := range names {
+		task := cfg.Tasks[name]
+		line := fmt.Sprintf("  %-20s %s", name, task.Description)
+		if task.Category != "" {
+			line += fmt.Sprintf(" [%s]", task.Category)
+		}
+		_, _ = fmt.Fprintln(w, strings.TrimRight(line, " "))
+	}
+}
@@ -2,9 +2,16 @@ package core
 
 import (
 	"bytes"
+	"context"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gtithub.com/jgfranco17/opsrunner/cli/executor"
 )
 
 type CliCommandFunction func() *cobra.Command
@@ -31,3 +38,119 @@ func ExecuteTestCommand(t *testing.T, cmd *cobra.Command, args ...string) CliRun
 		Error:       err,
 	}
 }
+
+// recordingExecutor records the commands it runs and the working
+// directory they run in.
+type recordingExecutor struct {
+	commands []string
+	dirs     []string
+}
+
+func (e *recordingExecutor) Exec(ctx context.Context, command executor.Command) (executor.Result, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return executor.Result{}, err
+	}
+	e.commands = append(e.commands, command.Run)
+	e.dirs = append(e.dirs, dir)
+	return executor.Result{}, nil
+}
+
+const testDefinition = `---
+name: demo
+version: 1.0.0
+codebase:
+  build:
+    steps: [make]
+tasks:
+  lint:
+    description: Run the linters
+    category: checks
+    steps: [make lint]
+  test:
+    steps: [make test]
+`
+
+// setupProject writes a definition file at the root of a new git
+// repository and moves into its sub/dir directory for the duration of the
+// test. It returns the root of the repository.
+func setupProject(t *testing.T, definition string) string {
+	t.Helper()
+	root, err := filepath.EvalSymlinks(t.TempDir())
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Join(root, ".git"), 0o755))
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "sub", "dir"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, ".opsrunner.yaml"), []byte(definition), 0o644))
+
+	cwd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(filepath.Join(root, "sub", "dir")))
+	t.Cleanup(func() {
+		_ = os.Chdir(cwd)
+	})
+	return root
+}
+
+func TestRunDiscoversDefinitionInParent(t *testing.T) {
+	root := setupProject(t, testDefinition)
+	exec := &recordingExecutor{}
+
+	result := ExecuteTestCommand(t, GetRunCommand(exec), "lint")
+	require.NoError(t, result.Error)
+	assert.Equal(t, []string{"make lint"}, exec.commands)
+	assert.Equal(t, []string{root}, exec.dirs)
+}
+
+func TestRunNoChdir(t *testing.T) {
+	root := setupProject(t, testDefinition)
+	exec := &recordingExecutor{}
+
+	result := ExecuteTestCommand(t, GetRunCommand(exec), "lint", "--no-chdir")
+	require.NoError(t, result.Error)
+	assert.Equal(t, []string{filepath.Join(root, "sub", "dir")}, exec.dirs)
+}
+
+func TestRunListsTasks(t *testing.T) {
+	setupProject(t, testDefinition)
+	exec := &recordingExecutor{}
+
+	result := ExecuteTestCommand(t, GetRunCommand(exec))
+	require.NoError(t, result.Error)
+	assert.Equal(t, "Available tasks:\n  lint                 Run the linters [checks]\n  test\n", result.ShellOutput)
+	assert.Empty(t, exec.commands)
+}
+
+func TestValidateReportsDiagnostics(t *testing.T) {
+	setupProject(t, `---
+name: demo
+version: latest
+codebase:
+  build:
+    steps: [make]
+`)
+	path := filepath.Join("..", "..", ".opsrunner.yaml")
+
+	result := ExecuteTestCommand(t, GetValidateCommand())
+	assert.EqualError(t, result.Error, "validation failed: 1 problem(s) found in "+path)
+	assert.Equal(t, path+":3:1: version: 'latest' is not a semantic version (e.g. 1.2.3)\n", result.ShellOutput)
+}
+
+func TestValidateValidDefinition(t *testing.T) {
+	setupProject(t, testDefinition)
+	path := filepath.Join("..", "..", ".opsrunner.yaml")
+
+	result := ExecuteTestCommand(t, GetValidateCommand())
+	require.NoError(t, result.Error)
+	assert.Equal(t, path+" is valid\n", result.ShellOutput)
+}
+
+func TestCacheClear(t *testing.T) {
+	root := setupProject(t, testDefinition)
+	state := filepath.Join(root, ".opsrunner")
+	require.NoError(t, os.MkdirAll(filepath.Join(state, "tasks"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(state, "tasks", "lint.json"), []byte("{}"), 0o644))
+
+	result := ExecuteTestCommand(t, GetCacheCommand(), "clear")
+	require.NoError(t, result.Error)
+	assert.NoDirExists(t, state)
+}
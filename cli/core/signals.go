@@ -0,0 +1,36 @@
+package core
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"gtithub.com/jgfranco17/opsrunner/cli/executor"
+	"gtithub.com/jgfranco17/opsrunner/cli/logging"
+)
+
+// withSignalHandling returns a context that is cancelled when the process
+// receives SIGINT or SIGTERM. The signal is recorded as the cancellation
+// cause so that it can be forwarded to the running steps. Further signals
+// are ignored while the steps shut down within their grace period.
+func withSignalHandling(ctx context.Context) (context.Context, context.CancelFunc) {
+	logger := logging.FromContext(ctx)
+	ctx, cancel := context.WithCancelCause(ctx)
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	stop := make(chan struct{})
+	go func() {
+		select {
+		case sig := <-signals:
+			logger.Warnf("Received %s, stopping running steps", sig)
+			cancel(&executor.InterruptError{Signal: sig})
+		case <-stop:
+		}
+	}()
+	return ctx, func() {
+		signal.Stop(signals)
+		close(stop)
+		cancel(nil)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"syscall"
@@ -27,18 +28,52 @@ func (r *Result) PrintStdErr() {
 	}
 }
 
+// Command describes a single shell invocation.
+type Command struct {
+	// Run is the script passed to the shell.
+	Run string
+	// Shell is the program running the script: the name of a known shell,
+	// such as sh or python3, or a template like "python3 -u {0}" where {0}
+	// stands for a file holding the script. Empty means DefaultShell.
+	Shell string
+	// Strict makes the script stop at the first failing command, e.g. with
+	// set -euo pipefail for bash.
+	Strict bool
+	// Dir is the working directory, defaulting to the current one.
+	Dir string
+	// Env is the complete environment of the invocation as KEY=VALUE
+	// pairs. If nil, the executor's own environment is used.
+	Env []string
+	// Stdout and Stderr receive the output of the command while it runs,
+	// in addition to it being captured in the Result.
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
 type DefaultExecutor struct {
+	// Env is the environment used for commands that do not set their own.
+	// If nil, commands inherit the environment of the current process.
 	Env []string
 }
 
-func (c *DefaultExecutor) Exec(ctx context.Context, command string) (Result, error) {
+func (c *DefaultExecutor) Exec(ctx context.Context, command Command) (Result, error) {
 	var stdoutBuf, stderrBuf bytes.Buffer
 
-	cmd := exec.CommandContext(ctx, "bash", "-c", command)
-	cmd.Stdout = &stdoutBuf
-	cmd.Stderr = &stderrBuf
+	args, cleanup, err := shellArgs(command)
+	if err != nil {
+		return Result{ExitCode: -1}, err
+	}
+	defer cleanup()
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Dir = command.Dir
+	cmd.Env = c.Env
+	if command.Env != nil {
+		cmd.Env = command.Env
+	}
+	cmd.Stdout = teeWriter(&stdoutBuf, command.Stdout)
+	cmd.Stderr = teeWriter(&stderrBuf, command.Stderr)
 
-	err := cmd.Run()
+	err = runInProcessGroup(ctx, cmd)
 
 	exitCode := 0
 	if err != nil {
@@ -63,10 +98,10 @@ func (c *DefaultExecutor) Exec(ctx context.Context, command string) (Result, err
 	}, err
 }
 
-func (c *DefaultExecutor) AddEnv(envs []string) {
-	baseEnv := os.Environ()
-	if len(envs) > 0 {
-		baseEnv = append(baseEnv, envs...)
+// teeWriter duplicates writes to the sink, if one is given.
+func teeWriter(capture io.Writer, sink io.Writer) io.Writer {
+	if sink == nil {
+		return capture
 	}
-	c.Env = baseEnv
+	return io.MultiWriter(capture, sink)
 }
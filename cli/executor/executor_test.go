@@ -0,0 +1,119 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecStreamsAndCapturesOutput(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	executor := &DefaultExecutor{}
+
+	result, err := executor.Exec(context.Background(), Command{
+		Run:    "echo out; echo err >&2",
+		Stdout: &stdout,
+		Stderr: &stderr,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, result.ExitCode)
+	assert.Equal(t, "out\n", result.Stdout)
+	assert.Equal(t, "err\n", result.Stderr)
+	assert.Equal(t, "out\n", stdout.String())
+	assert.Equal(t, "err\n", stderr.String())
+}
+
+func TestExecUsesCommandEnvAndDir(t *testing.T) {
+	dir := t.TempDir()
+	executor := &DefaultExecutor{}
+
+	result, err := executor.Exec(context.Background(), Command{
+		Run: `echo "$GREETING from $(pwd)"`,
+		Dir: dir,
+		Env: []string{"GREETING=hello"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "hello from "+dir+"\n", result.Stdout)
+}
+
+func TestExecReportsExitCode(t *testing.T) {
+	executor := &DefaultExecutor{}
+
+	result, err := executor.Exec(context.Background(), Command{Run: "exit 3"})
+	assert.Error(t, err)
+	assert.Equal(t, 3, result.ExitCode)
+}
+
+func TestExecCancelKillsProcessGroup(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "pid")
+	executor := &DefaultExecutor{}
+	ctx, cancel := context.WithCancelCause(WithGracePeriod(context.Background(), 100*time.Millisecond))
+	time.AfterFunc(300*time.Millisecond, func() {
+		cancel(&InterruptError{Signal: syscall.SIGINT})
+	})
+
+	start := time.Now()
+	// The grandchild ignores the forwarded signal and must be killed.
+	_, err := executor.Exec(ctx, Command{
+		Run: fmt.Sprintf(`bash -c 'trap "" INT TERM; echo $$ > %s; sleep 30' & wait`, marker),
+	})
+	assert.Less(t, time.Since(start), 5*time.Second)
+
+	var cancelled *CancelledError
+	require.True(t, errors.As(err, &cancelled))
+	var interrupt *InterruptError
+	require.True(t, errors.As(err, &interrupt))
+	assert.Equal(t, syscall.SIGINT, interrupt.Signal)
+
+	content, readErr := os.ReadFile(marker)
+	require.NoError(t, readErr)
+	pid, convErr := strconv.Atoi(strings.TrimSpace(string(content)))
+	require.NoError(t, convErr)
+	assert.Eventually(t, func() bool {
+		return !processAlive(pid)
+	}, time.Second, 10*time.Millisecond, "grandchild process should have been killed")
+}
+
+// expiredAfterRun is a context whose deadline passes without Done firing
+// while the command runs, like a deadline reached as the command exits.
+type expiredAfterRun struct {
+	context.Context
+}
+
+func (expiredAfterRun) Err() error {
+	return context.DeadlineExceeded
+}
+
+func TestExecKeepsResultWhenNotStopped(t *testing.T) {
+	executor := &DefaultExecutor{}
+
+	result, err := executor.Exec(expiredAfterRun{context.Background()}, Command{Run: "echo done"})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, result.ExitCode)
+	assert.Equal(t, "done\n", result.Stdout)
+}
+
+// processAlive reports whether the process exists and is not a zombie
+// waiting to be reaped.
+func processAlive(pid int) bool {
+	if syscall.Kill(pid, 0) != nil {
+		return false
+	}
+	stat, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return true
+	}
+	fields := strings.Fields(string(stat[strings.LastIndexByte(string(stat), ')')+1:]))
+	return len(fields) == 0 || fields[0] != "Z"
+}
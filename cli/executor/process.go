@@ -0,0 +1,116 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// DefaultGracePeriod is how long a cancelled command is given to exit
+// before its process group is killed.
+const DefaultGracePeriod = 10 * time.Second
+
+// InterruptError is used as the cancellation cause of a context when the
+// run is interrupted by a signal, so that the signal can be forwarded to
+// the running commands.
+type InterruptError struct {
+	Signal os.Signal
+}
+
+func (e *InterruptError) Error() string {
+	return fmt.Sprintf("interrupted by %s", e.Signal)
+}
+
+// CancelledError is returned when a command is stopped because its
+// context was cancelled. It wraps the cancellation cause.
+type CancelledError struct {
+	Cause error
+}
+
+func (e *CancelledError) Error() string {
+	return fmt.Sprintf("command cancelled: %v", e.Cause)
+}
+
+func (e *CancelledError) Unwrap() error {
+	return e.Cause
+}
+
+type gracePeriodKey string
+
+const gracePeriodKeyName gracePeriodKey = "gracePeriod"
+
+// WithGracePeriod sets how long cancelled commands started with the
+// returned context are given to exit before being killed.
+func WithGracePeriod(ctx context.Context, gracePeriod time.Duration) context.Context {
+	return context.WithValue(ctx, gracePeriodKeyName, gracePeriod)
+}
+
+// GracePeriodFromContext retrieves the grace period from the context, or
+// returns DefaultGracePeriod if not found.
+func GracePeriodFromContext(ctx context.Context) time.Duration {
+	if gracePeriod, ok := ctx.Value(gracePeriodKeyName).(time.Duration); ok {
+		return gracePeriod
+	}
+	return DefaultGracePeriod
+}
+
+// runInProcessGroup starts the command in its own process group and waits
+// for it. If the context is cancelled first, the whole group receives the
+// interrupting signal (SIGTERM by default) and, if it is still running
+// after the grace period, SIGKILL.
+func runInProcessGroup(ctx context.Context, cmd *exec.Cmd) error {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	// signalled is only set once the group was actually told to stop, so
+	// that a command finishing right as the context ends keeps its result.
+	signalled := false
+	go func() {
+		defer close(stopped)
+		select {
+		case <-done:
+			return
+		case <-ctx.Done():
+		}
+		select {
+		case <-done:
+			return
+		default:
+		}
+		signalled = true
+		stopProcessGroup(ctx, cmd.Process.Pid, done)
+	}()
+	err := cmd.Wait()
+	close(done)
+	<-stopped
+	if signalled {
+		return &CancelledError{Cause: context.Cause(ctx)}
+	}
+	return err
+}
+
+// stopProcessGroup sends the interrupting signal of the cancelled context,
+// SIGTERM by default, to the process group and SIGKILL if it has not exited
+// by the end of the grace period.
+func stopProcessGroup(ctx context.Context, pid int, exited <-chan struct{}) {
+	signal := syscall.SIGTERM
+	if interrupt, ok := context.Cause(ctx).(*InterruptError); ok {
+		if sig, ok := interrupt.Signal.(syscall.Signal); ok {
+			signal = sig
+		}
+	}
+	_ = syscall.Kill(-pid, signal)
+	timer := time.NewTimer(GracePeriodFromContext(ctx))
+	defer timer.Stop()
+	select {
+	case <-exited:
+	case <-timer.C:
+		_ = syscall.Kill(-pid, syscall.SIGKILL)
+	}
+}
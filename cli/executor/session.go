@@ -0,0 +1,390 @@
+package executor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// sessionLoop runs the NUL-terminated scripts read from file descriptor 3
+// in the shell itself, so that their changes to its state persist.
+const sessionLoop = `while IFS= read -r -d '' __opsrunner_script <&3; do eval "$__opsrunner_script"; done`
+
+// sessionIgnoredEnv are variables the shell maintains itself, which are
+// never exported into a running session.
+var sessionIgnoredEnv = []string{"PWD", "OLDPWD", "SHLVL", "_", "SHELLOPTS", "BASHOPTS"}
+
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Session runs commands one after another in a single long-lived bash
+// process, so that directory changes, shell functions and exported
+// variables of a command carry over to the next ones. The output of each
+// command is delimited with markers, keeping the output and exit code of
+// every command separate.
+//
+// The shell starts with the first command. Once it has exited, because a
+// command called exit or was cancelled, every later command fails with the
+// reason. A Session runs one command at a time.
+type Session struct {
+	// Env is the environment the shell starts with when the first command
+	// does not set its own. If nil, the shell inherits the environment of
+	// the current process.
+	Env []string
+
+	mu       sync.Mutex
+	cmd      *exec.Cmd
+	scripts  *os.File
+	statuses chan int
+	stdout   *sessionStream
+	stderr   *sessionStream
+	exited   chan struct{}
+	waitErr  error
+	// marker is split in two so that tracing the command printing it, as
+	// with set -x, does not print the marker itself.
+	marker [2]string
+	// env is the environment the previous command was given.
+	env   []string
+	ended *SessionEndedError
+}
+
+// SessionEndedError is returned for the commands sent to a session whose
+// shell has already exited. It wraps the reason the shell exited.
+type SessionEndedError struct {
+	Cause error
+}
+
+func (e *SessionEndedError) Error() string {
+	return fmt.Sprintf("shell session ended: %v", e.Cause)
+}
+
+func (e *SessionEndedError) Unwrap() error {
+	return e.Cause
+}
+
+// NewSession creates a session using the environment of the executor.
+func (c *DefaultExecutor) NewSession() *Session {
+	return &Session{Env: c.Env}
+}
+
+// Exec runs the command in the shell of the session, starting it first if
+// needed. Cancelling the context stops the shell, ending the session.
+func (s *Session) Exec(ctx context.Context, command Command) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.ended != nil {
+		return Result{ExitCode: -1}, s.ended
+	}
+	if command.Shell != "" && command.Shell != DefaultShell {
+		return Result{ExitCode: -1}, fmt.Errorf("shell sessions only run %s, not '%s'", DefaultShell, command.Shell)
+	}
+	if command.Strict {
+		return Result{ExitCode: -1}, errors.New("strict mode is not supported in shell sessions")
+	}
+	env := command.Env
+	if env == nil {
+		env = s.Env
+	}
+	if env == nil {
+		env = os.Environ()
+	}
+	if s.cmd == nil {
+		if err := s.start(env); err != nil {
+			return Result{ExitCode: -1}, err
+		}
+	}
+	script, err := s.script(command, env)
+	if err != nil {
+		return Result{ExitCode: -1}, err
+	}
+	s.env = env
+
+	var stdoutBuf, stderrBuf bytes.Buffer
+	s.stdout.attach(teeWriter(&stdoutBuf, command.Stdout))
+	s.stderr.attach(teeWriter(&stderrBuf, command.Stderr))
+	exitCode, err := s.run(ctx, script)
+	s.stdout.attach(nil)
+	s.stderr.attach(nil)
+	return Result{
+		Stdout:   stdoutBuf.String(),
+		Stderr:   stderrBuf.String(),
+		ExitCode: exitCode,
+	}, err
+}
+
+// Close ends the session and waits for the shell to exit.
+func (s *Session) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cmd == nil {
+		return nil
+	}
+	_ = s.scripts.Close()
+	<-s.exited
+	s.cmd = nil
+	if s.ended != nil {
+		return nil
+	}
+	s.ended = &SessionEndedError{Cause: errors.New("closed")}
+	return s.waitErr
+}
+
+func (s *Session) start(env []string) error {
+	token := make([]byte, 8)
+	if _, err := rand.Read(token); err != nil {
+		return fmt.Errorf("failed to start shell session: %w", err)
+	}
+	s.marker = [2]string{"__opsrunner_session_", hex.EncodeToString(token) + "__"}
+
+	var pipes [4][2]*os.File
+	for idx := range pipes {
+		r, w, err := os.Pipe()
+		if err != nil {
+			closeFiles(pipes[:idx]...)
+			return fmt.Errorf("failed to start shell session: %w", err)
+		}
+		pipes[idx] = [2]*os.File{r, w}
+	}
+	scripts, statuses, stdout, stderr := pipes[0], pipes[1], pipes[2], pipes[3]
+
+	cmd := exec.Command("bash", "--noprofile", "--norc", "-c", sessionLoop)
+	cmd.Env = env
+	cmd.Stdout = stdout[1]
+	cmd.Stderr = stderr[1]
+	cmd.ExtraFiles = []*os.File{scripts[0], statuses[1]}
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	err := cmd.Start()
+	_ = scripts[0].Close()
+	_ = statuses[1].Close()
+	_ = stdout[1].Close()
+	_ = stderr[1].Close()
+	if err != nil {
+		_ = scripts[1].Close()
+		_ = statuses[0].Close()
+		_ = stdout[0].Close()
+		_ = stderr[0].Close()
+		return fmt.Errorf("failed to start shell session: %w", err)
+	}
+
+	marker := []byte(s.marker[0] + s.marker[1] + "\n")
+	s.cmd = cmd
+	s.scripts = scripts[1]
+	s.stdout = newSessionStream(stdout[0], marker)
+	s.stderr = newSessionStream(stderr[0], marker)
+	s.statuses = make(chan int, 1)
+	go func() {
+		defer close(s.statuses)
+		defer statuses[0].Close()
+		scanner := bufio.NewScanner(statuses[0])
+		for scanner.Scan() {
+			code, _ := strconv.Atoi(scanner.Text())
+			s.statuses <- code
+		}
+	}()
+	s.exited = make(chan struct{})
+	go func() {
+		s.waitErr = cmd.Wait()
+		close(s.exited)
+	}()
+	return nil
+}
+
+// run sends the script to the shell and waits for its exit code.
+func (s *Session) run(ctx context.Context, script string) (int, error) {
+	// A failed write means the shell is gone, which closes the statuses.
+	_, _ = io.WriteString(s.scripts, script+"\x00")
+	select {
+	case code, ok := <-s.statuses:
+		if ok {
+			<-s.stdout.done
+			<-s.stderr.done
+			if code != 0 {
+				return code, fmt.Errorf("exit status %d", code)
+			}
+			return 0, nil
+		}
+		// The command made the shell exit.
+		<-s.exited
+		s.drain()
+		code = exitCode(s.waitErr)
+		s.ended = &SessionEndedError{Cause: fmt.Errorf("exit code %d", code)}
+		return code, s.waitErr
+	case <-ctx.Done():
+		stopProcessGroup(ctx, s.cmd.Process.Pid, s.exited)
+		<-s.exited
+		s.drain()
+		err := &CancelledError{Cause: context.Cause(ctx)}
+		s.ended = &SessionEndedError{Cause: err}
+		return -1, err
+	}
+}
+
+// drain waits for the output of the exited shell to be fully read.
+func (s *Session) drain() {
+	for range s.stdout.done {
+	}
+	for range s.stderr.done {
+	}
+}
+
+// script wraps the command so that the shell runs it with the given
+// environment, then prints the markers and reports its exit code on file
+// descriptor 4.
+func (s *Session) script(command Command, env []string) (string, error) {
+	var sb strings.Builder
+	for _, line := range envChanges(s.env, env) {
+		sb.WriteString(line + "\n")
+	}
+	run := "eval " + shellQuote(command.Run) + " 3<&- 4>&-"
+	if command.Dir != "" {
+		// Relative directories are resolved like for a new shell, rather
+		// than against the current directory of the session.
+		dir, err := filepath.Abs(command.Dir)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString("__opsrunner_dir=$PWD\n")
+		run = "cd -- " + shellQuote(dir) + " && " + run
+	}
+	sb.WriteString(run + "\n__opsrunner_status=$?\n")
+	if command.Dir != "" {
+		sb.WriteString("cd -- \"$__opsrunner_dir\"\n")
+	}
+	marker := fmt.Sprintf("printf '%%s%%s\\n' %s %s", s.marker[0], s.marker[1])
+	sb.WriteString(marker + "\n" + marker + " >&2\n")
+	sb.WriteString("printf '%s\\n' \"$__opsrunner_status\" >&4")
+	return sb.String(), nil
+}
+
+// envChanges returns the commands turning the previous environment of the
+// session into the next one.
+func envChanges(previous []string, next []string) []string {
+	values := make(map[string]string, len(previous))
+	for _, pair := range previous {
+		key, value, _ := strings.Cut(pair, "=")
+		values[key] = value
+	}
+	ignored := func(key string) bool {
+		return !envNamePattern.MatchString(key) || slices.Contains(sessionIgnoredEnv, key)
+	}
+	var lines []string
+	kept := make(map[string]bool, len(next))
+	for _, pair := range next {
+		key, value, _ := strings.Cut(pair, "=")
+		kept[key] = true
+		if current, ok := values[key]; ignored(key) || (ok && current == value) {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("export %s=%s", key, shellQuote(value)))
+	}
+	for _, pair := range previous {
+		key, _, _ := strings.Cut(pair, "=")
+		if !kept[key] && !ignored(key) {
+			lines = append(lines, "unset -v "+key)
+		}
+	}
+	return lines
+}
+
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+func exitCode(err error) int {
+	var exitErr *exec.ExitError
+	if err == nil {
+		return 0
+	} else if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	return -1
+}
+
+func closeFiles(pipes ...[2]*os.File) {
+	for _, pipe := range pipes {
+		_ = pipe[0].Close()
+		_ = pipe[1].Close()
+	}
+}
+
+// sessionStream forwards the output of the shell to the writer of the
+// running command, signalling done at every marker and closing it once the
+// shell has exited.
+type sessionStream struct {
+	marker []byte
+	done   chan struct{}
+	mu     sync.Mutex
+	out    io.Writer
+}
+
+func newSessionStream(r io.ReadCloser, marker []byte) *sessionStream {
+	stream := &sessionStream{marker: marker, done: make(chan struct{}, 1)}
+	go stream.read(r)
+	return stream
+}
+
+func (s *sessionStream) attach(out io.Writer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.out = out
+}
+
+// write forwards output to the attached writer. Output written while no
+// command runs, e.g. by background jobs, is dropped.
+func (s *sessionStream) write(p []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.out != nil && len(p) > 0 {
+		_, _ = s.out.Write(p)
+	}
+}
+
+func (s *sessionStream) read(r io.ReadCloser) {
+	defer close(s.done)
+	defer r.Close()
+
+	var pending []byte
+	chunk := make([]byte, 32*1024)
+	for {
+		n, err := r.Read(chunk)
+		pending = append(pending, chunk[:n]...)
+		for {
+			idx := bytes.Index(pending, s.marker)
+			if idx < 0 {
+				break
+			}
+			s.write(pending[:idx])
+			pending = pending[idx+len(s.marker):]
+			s.done <- struct{}{}
+		}
+		if err != nil {
+			s.write(pending)
+			return
+		}
+		// Hold back what could be the start of a marker.
+		keep := 0
+		for k := min(len(pending), len(s.marker)-1); k > 0; k-- {
+			if bytes.HasSuffix(pending, s.marker[:k]) {
+				keep = k
+				break
+			}
+		}
+		s.write(pending[:len(pending)-keep])
+		pending = slices.Clone(pending[len(pending)-keep:])
+	}
+}
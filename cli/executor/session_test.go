@@ -0,0 +1,107 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionKeepsShellState(t *testing.T) {
+	dir := t.TempDir()
+	session := (&DefaultExecutor{}).NewSession()
+	defer session.Close()
+
+	for _, run := range []string{
+		"cd " + dir,
+		"export GREETING=hello",
+		`greet() { echo "$GREETING from $(pwd)"; }`,
+	} {
+		_, err := session.Exec(context.Background(), Command{Run: run})
+		require.NoError(t, err)
+	}
+	result, err := session.Exec(context.Background(), Command{Run: "greet"})
+	require.NoError(t, err)
+	assert.Equal(t, "hello from "+dir+"\n", result.Stdout)
+}
+
+func TestSessionSeparatesOutputAndExitCodes(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	session := (&DefaultExecutor{}).NewSession()
+	defer session.Close()
+
+	result, err := session.Exec(context.Background(), Command{
+		Run:    "echo out; printf partial; echo err >&2; false",
+		Stdout: &stdout,
+		Stderr: &stderr,
+	})
+	assert.EqualError(t, err, "exit status 1")
+	assert.Equal(t, 1, result.ExitCode)
+	assert.Equal(t, "out\npartial", result.Stdout)
+	assert.Equal(t, "err\n", result.Stderr)
+	assert.Equal(t, "out\npartial", stdout.String())
+
+	result, err = session.Exec(context.Background(), Command{Run: "set -x; echo next; set +x"})
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.ExitCode)
+	assert.Equal(t, "next\n", result.Stdout)
+}
+
+func TestSessionAppliesCommandEnvAndDir(t *testing.T) {
+	dir := t.TempDir()
+	session := &Session{Env: []string{"PATH=" + os.Getenv("PATH"), "STAGE=base"}}
+	defer session.Close()
+
+	result, err := session.Exec(context.Background(), Command{
+		Run: `echo "$STAGE $STEP_ONLY $(pwd)"`,
+		Dir: dir,
+		Env: []string{"PATH=" + os.Getenv("PATH"), "STAGE=it's set", "STEP_ONLY=1"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "it's set 1 "+dir+"\n", result.Stdout)
+
+	// Variables of the previous command only are removed, and the
+	// directory of a command does not stick.
+	cwd, err := os.Getwd()
+	require.NoError(t, err)
+	result, err = session.Exec(context.Background(), Command{Run: `echo "$STAGE ${STEP_ONLY:-unset} $(pwd)"`})
+	require.NoError(t, err)
+	assert.Equal(t, "base unset "+cwd+"\n", result.Stdout)
+}
+
+func TestSessionEndsWhenShellExits(t *testing.T) {
+	session := (&DefaultExecutor{}).NewSession()
+	defer session.Close()
+
+	result, err := session.Exec(context.Background(), Command{Run: "echo bye; exit 3"})
+	assert.Error(t, err)
+	assert.Equal(t, 3, result.ExitCode)
+	assert.Equal(t, "bye\n", result.Stdout)
+
+	_, err = session.Exec(context.Background(), Command{Run: "true"})
+	assert.EqualError(t, err, "shell session ended: exit code 3")
+}
+
+func TestSessionCancelStopsHungCommand(t *testing.T) {
+	session := (&DefaultExecutor{}).NewSession()
+	defer session.Close()
+
+	ctx, cancel := context.WithTimeout(WithGracePeriod(context.Background(), 100*time.Millisecond), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	result, err := session.Exec(ctx, Command{Run: "echo started; sleep 30"})
+	assert.Less(t, time.Since(start), 5*time.Second)
+
+	var cancelled *CancelledError
+	require.True(t, errors.As(err, &cancelled), "expected a cancelled error, got %v", err)
+	assert.Equal(t, -1, result.ExitCode)
+	assert.Equal(t, "started\n", result.Stdout)
+
+	_, err = session.Exec(context.Background(), Command{Run: "true"})
+	assert.ErrorContains(t, err, "shell session ended: command cancelled")
+}
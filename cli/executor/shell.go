@@ -0,0 +1,111 @@
+package executor
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// DefaultShell runs the commands that do not select a shell.
+const DefaultShell = "bash"
+
+// shellSpec describes how a shell runs a script file.
+type shellSpec struct {
+	// template is the command line, where {0} stands for the script file.
+	template string
+	// extension is given to the script file, for interpreters that care.
+	extension string
+	// strict is prepended to scripts run in strict mode. Shells without it
+	// do not support strict mode.
+	strict string
+}
+
+var shells = map[string]shellSpec{
+	"bash":    {template: "bash {0}", extension: ".sh", strict: "set -euo pipefail"},
+	"sh":      {template: "sh {0}", extension: ".sh", strict: "set -eu"},
+	"zsh":     {template: "zsh {0}", extension: ".sh", strict: "set -euo pipefail"},
+	"python3": {template: "python3 {0}", extension: ".py"},
+	"node":    {template: "node {0}", extension: ".js"},
+}
+
+// ShellNames returns the names of the known shells in alphabetical order.
+func ShellNames() []string {
+	return slices.Sorted(maps.Keys(shells))
+}
+
+// CheckShell reports whether the shell is a known one or a template, and
+// whether it supports strict mode if requested.
+func CheckShell(shell string, strict bool) error {
+	spec, err := parseShell(shell)
+	if err != nil {
+		return err
+	}
+	if strict && spec.strict == "" {
+		return fmt.Errorf("strict mode is not supported by shell '%s'", shell)
+	}
+	return nil
+}
+
+// parseShell returns the spec of a known shell, or of a template such as
+// "python3 -u {0}". Templates of known programs share their extension and
+// strict mode.
+func parseShell(shell string) (shellSpec, error) {
+	if spec, ok := shells[shell]; ok {
+		return spec, nil
+	}
+	if !strings.Contains(shell, "{0}") {
+		return shellSpec{}, fmt.Errorf("unknown shell '%s' (expected one of: %s, or a template containing {0})",
+			shell, strings.Join(ShellNames(), ", "))
+	}
+	spec := shellSpec{template: shell}
+	if known, ok := shells[filepath.Base(strings.Fields(shell)[0])]; ok {
+		spec.extension, spec.strict = known.extension, known.strict
+	}
+	return spec, nil
+}
+
+// shellArgs returns the command line running the script of the command.
+// Single-line scripts for the default shell are passed to bash -c; all
+// others are written to a temporary file, which the returned function
+// removes.
+func shellArgs(command Command) ([]string, func(), error) {
+	shell := command.Shell
+	if shell == "" {
+		shell = DefaultShell
+	}
+	if err := CheckShell(shell, command.Strict); err != nil {
+		return nil, nil, err
+	}
+	spec, _ := parseShell(shell)
+	script := command.Run
+	if command.Strict {
+		script = spec.strict + "\n" + script
+	} else if command.Shell == "" && !strings.Contains(strings.TrimSuffix(script, "\n"), "\n") {
+		return []string{"bash", "-c", script}, func() {}, nil
+	}
+
+	file, err := os.CreateTemp("", "opsrunner-script-*"+spec.extension)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to write script: %w", err)
+	}
+	cleanup := func() { _ = os.Remove(file.Name()) }
+	if !strings.HasSuffix(script, "\n") {
+		script += "\n"
+	}
+	_, err = file.WriteString(script)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		cleanup()
+		return nil, nil, fmt.Errorf("failed to write script: %w", err)
+	}
+	args := strings.Fields(spec.template)
+	for idx := range args {
+		args[idx] = strings.ReplaceAll(args[idx], "{0}", file.Name())
+	}
+	return args, cleanup, nil
+}
@@ -0,0 +1,66 @@
+package executor
+
+import (
+	"context"
+	"os/exec"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckShell(t *testing.T) {
+	assert.NoError(t, CheckShell("sh", true))
+	assert.NoError(t, CheckShell("python3 -u {0}", false))
+	assert.NoError(t, CheckShell("/usr/bin/bash --norc {0}", true))
+	assert.EqualError(t, CheckShell("fish", false),
+		"unknown shell 'fish' (expected one of: bash, node, python3, sh, zsh, or a template containing {0})")
+	assert.EqualError(t, CheckShell("python3", true), "strict mode is not supported by shell 'python3'")
+}
+
+func TestExecMultiLineScript(t *testing.T) {
+	executor := &DefaultExecutor{}
+
+	result, err := executor.Exec(context.Background(), Command{Run: "greeting=hello\necho \"$greeting\"\necho \"$0\" | grep -q opsrunner-script\n"})
+	require.NoError(t, err)
+	assert.Equal(t, "hello\n", result.Stdout)
+}
+
+func TestExecWithShell(t *testing.T) {
+	executor := &DefaultExecutor{}
+
+	result, err := executor.Exec(context.Background(), Command{Run: "echo $0", Shell: "sh"})
+	require.NoError(t, err)
+	assert.Regexp(t, `opsrunner-script-\d+\.sh\n$`, result.Stdout)
+
+	result, err = executor.Exec(context.Background(), Command{Run: "echo from template", Shell: "bash --norc {0}"})
+	require.NoError(t, err)
+	assert.Equal(t, "from template\n", result.Stdout)
+}
+
+func TestExecWithInterpreter(t *testing.T) {
+	if _, err := exec.LookPath("python3"); err != nil {
+		t.Skip("python3 is not installed")
+	}
+	executor := &DefaultExecutor{}
+
+	result, err := executor.Exec(context.Background(), Command{Run: "import sys\nprint(sys.argv[0].endswith('.py'))", Shell: "python3"})
+	require.NoError(t, err)
+	assert.Equal(t, "True\n", result.Stdout)
+}
+
+func TestExecStrictMode(t *testing.T) {
+	executor := &DefaultExecutor{}
+
+	result, err := executor.Exec(context.Background(), Command{Run: "false | true\necho unreachable"})
+	require.NoError(t, err)
+	assert.Equal(t, "unreachable\n", result.Stdout)
+
+	result, err = executor.Exec(context.Background(), Command{Run: "false | true\necho unreachable", Strict: true})
+	assert.Error(t, err)
+	assert.Equal(t, 1, result.ExitCode)
+	assert.Empty(t, result.Stdout)
+
+	_, err = executor.Exec(context.Background(), Command{Run: "print(1)", Shell: "python3", Strict: true})
+	assert.EqualError(t, err, "strict mode is not supported by shell 'python3'")
+}
@@ -0,0 +1,179 @@
+package outputs
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Stream configures where command output is written while it runs and
+// how each line is decorated.
+type Stream struct {
+	Stdout     io.Writer
+	Stderr     io.Writer
+	Prefix     bool
+	Timestamps bool
+}
+
+// DefaultStream writes undecorated output to the standard streams.
+func DefaultStream() *Stream {
+	return &Stream{
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+	}
+}
+
+// Writers returns line writers for the standard output and error of a
+// command, decorated according to the stream settings.
+func (s *Stream) Writers(label string) (*LineWriter, *LineWriter) {
+	prefix := ""
+	if s.Prefix {
+		prefix = fmt.Sprintf("[%s] ", label)
+	}
+	return NewLineWriter(s.Stdout, prefix, s.Timestamps), NewLineWriter(s.Stderr, prefix, s.Timestamps)
+}
+
+type streamKey string
+
+const streamKeyName streamKey = "stream"
+
+// AddToContext adds a stream to the context for later retrieval
+func AddToContext(ctx context.Context, stream *Stream) context.Context {
+	return context.WithValue(ctx, streamKeyName, stream)
+}
+
+// FromContext retrieves the stream from the context, or returns the default stream if not found
+func FromContext(ctx context.Context) *Stream {
+	if stream, ok := ctx.Value(streamKeyName).(*Stream); ok {
+		return stream
+	}
+	return DefaultStream()
+}
+
+// LineWriter forwards complete lines to the underlying writer, adding an
+// optional prefix and timestamp to each one. Partial lines are held back
+// until they are completed or the writer is flushed.
+type LineWriter struct {
+	mu         sync.Mutex
+	out        io.Writer
+	prefix     string
+	timestamps bool
+	pending    []byte
+}
+
+// NewLineWriter creates a LineWriter on top of the given writer.
+func NewLineWriter(out io.Writer, prefix string, timestamps bool) *LineWriter {
+	return &LineWriter{
+		out:        out,
+		prefix:     prefix,
+		timestamps: timestamps,
+	}
+}
+
+func (w *LineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.pending = append(w.pending, p...)
+	for {
+		idx := bytes.IndexByte(w.pending, '\n')
+		if idx < 0 {
+			break
+		}
+		if err := w.writeLine(w.pending[:idx+1]); err != nil {
+			return len(p), err
+		}
+		w.pending = w.pending[idx+1:]
+	}
+	return len(p), nil
+}
+
+// Flush writes out any partial line still held by the writer.
+func (w *LineWriter) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.pending) == 0 {
+		return nil
+	}
+	line := append(w.pending, '\n')
+	w.pending = nil
+	return w.writeLine(line)
+}
+
+func (w *LineWriter) writeLine(line []byte) error {
+	if w.prefix == "" && !w.timestamps {
+		_, err := w.out.Write(line)
+		return err
+	}
+	decoration := w.prefix
+	if w.timestamps {
+		decoration = time.Now().Format(time.TimeOnly) + " " + decoration
+	}
+	_, err := w.out.Write(append([]byte(decoration), line...))
+	return err
+}
+
+// Recorder holds back the output written through its streams, keeping the
+// order of standard output and error, until it is replayed. It keeps the
+// output of concurrent commands from interleaving.
+type Recorder struct {
+	mu     sync.Mutex
+	chunks []recordedChunk
+}
+
+type recordedChunk struct {
+	stderr bool
+	data   []byte
+}
+
+// NewRecorder creates an empty Recorder.
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// Stream returns a stream decorated like the given one that writes into
+// the recorder.
+func (r *Recorder) Stream(s *Stream) *Stream {
+	return &Stream{
+		Stdout:     &recorderWriter{recorder: r},
+		Stderr:     &recorderWriter{recorder: r, stderr: true},
+		Prefix:     s.Prefix,
+		Timestamps: s.Timestamps,
+	}
+}
+
+// Replay writes the recorded output to the standard output and error of
+// the stream, without decorating it again.
+func (r *Recorder) Replay(s *Stream) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, chunk := range r.chunks {
+		out := s.Stdout
+		if chunk.stderr {
+			out = s.Stderr
+		}
+		if _, err := out.Write(chunk.data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type recorderWriter struct {
+	recorder *Recorder
+	stderr   bool
+}
+
+func (w *recorderWriter) Write(p []byte) (int, error) {
+	w.recorder.mu.Lock()
+	defer w.recorder.mu.Unlock()
+
+	w.recorder.chunks = append(w.recorder.chunks, recordedChunk{stderr: w.stderr, data: bytes.Clone(p)})
+	return len(p), nil
+}
@@ -0,0 +1,64 @@
+package outputs
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLineWriterPrefixesCompleteLines(t *testing.T) {
+	var buf bytes.Buffer
+	writer := NewLineWriter(&buf, "[test] ", false)
+
+	_, err := writer.Write([]byte("first\nsec"))
+	assert.NoError(t, err)
+	assert.Equal(t, "[test] first\n", buf.String())
+
+	_, err = writer.Write([]byte("ond\nthird"))
+	assert.NoError(t, err)
+	assert.Equal(t, "[test] first\n[test] second\n", buf.String())
+
+	assert.NoError(t, writer.Flush())
+	assert.Equal(t, "[test] first\n[test] second\n[test] third\n", buf.String())
+}
+
+func TestLineWriterTimestamps(t *testing.T) {
+	var buf bytes.Buffer
+	writer := NewLineWriter(&buf, "", true)
+
+	_, err := writer.Write([]byte("hello\n"))
+	assert.NoError(t, err)
+	assert.Regexp(t, regexp.MustCompile(`^\d{2}:\d{2}:\d{2} hello\n$`), buf.String())
+}
+
+func TestStreamFromContext(t *testing.T) {
+	assert.Equal(t, os.Stdout, FromContext(context.Background()).Stdout)
+
+	var stdout, stderr bytes.Buffer
+	stream := &Stream{Stdout: &stdout, Stderr: &stderr, Prefix: true}
+	ctx := AddToContext(context.Background(), stream)
+	out, errOut := FromContext(ctx).Writers("lint")
+	_, _ = out.Write([]byte("ok\n"))
+	_, _ = errOut.Write([]byte("warning\n"))
+	assert.Equal(t, "[lint] ok\n", stdout.String())
+	assert.Equal(t, "[lint] warning\n", stderr.String())
+}
+
+func TestRecorder(t *testing.T) {
+	recorder := NewRecorder()
+	stream := recorder.Stream(&Stream{Prefix: true})
+	stdout, stderr := stream.Writers("test")
+	_, _ = stdout.Write([]byte("compiling\n"))
+	_, _ = stderr.Write([]byte("warning\n"))
+	_, _ = stdout.Write([]byte("done\n"))
+
+	var out, errOut bytes.Buffer
+	require.NoError(t, recorder.Replay(&Stream{Stdout: &out, Stderr: &errOut}))
+	assert.Equal(t, "[test] compiling\n[test] done\n", out.String())
+	assert.Equal(t, "[test] warning\n", errOut.String())
+}
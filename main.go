@@ -1,6 +1,8 @@
 package main
 
 import (
+	"os"
+
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 
@@ -22,11 +24,16 @@ func main() {
 	command := core.NewCommandRegistry(projectName, projectDescription, version)
 	commandsList := []*cobra.Command{
 		core.GetBuildCommand(executor),
+		core.GetRunCommand(executor),
+		core.GetValidateCommand(),
+		core.GetSchemaCommand(),
+		core.GetCacheCommand(),
 	}
 	command.RegisterCommands(commandsList)
 
 	err := command.Execute()
 	if err != nil {
 		log.Error(err.Error())
+		os.Exit(1)
 	}
 }
@@ -37,16 +37,16 @@ var _ = Describe("User Stories Acceptance Tests", func() {
 						Language:     "go",
 						Dependencies: "go.mod",
 						Install: config.Operation{
-							Steps: []string{
-								"echo 'Installing dependencies...'",
-								"go mod tidy",
+							Steps: []config.Step{
+								{Run: "echo 'Installing dependencies...'"},
+								{Run: "go mod tidy"},
 							},
 						},
 						Build: config.Operation{
-							Steps: []string{
-								"echo 'Building project...'",
-								"echo 'go build -o myapp'",
-								"echo 'Build completed successfully'",
+							Steps: []config.Step{
+								{Run: "echo 'Building project...'"},
+								{Run: "echo 'go build -o myapp'"},
+								{Run: "echo 'Build completed successfully'"},
 							},
 						},
 					},
@@ -79,15 +79,15 @@ var _ = Describe("User Stories Acceptance Tests", func() {
 					Codebase: config.Codebase{
 						Language: "go",
 						Install: config.Operation{
-							Steps: []string{
-								"echo 'This should be skipped'",
-								"go mod download",
+							Steps: []config.Step{
+								{Run: "echo 'This should be skipped'"},
+								{Run: "go mod download"},
 							},
 						},
 						Build: config.Operation{
-							Steps: []string{
-								"echo 'Building without install'",
-								"echo 'go build'",
+							Steps: []config.Step{
+								{Run: "echo 'Building without install'"},
+								{Run: "echo 'go build'"},
 							},
 						},
 					},
@@ -116,10 +116,10 @@ var _ = Describe("User Stories Acceptance Tests", func() {
 						Language: "go",
 						Build: config.Operation{
 							FailFast: true,
-							Steps: []string{
-								"echo 'Step 1 - This will succeed'",
-								"exit 1", // This will fail
-								"echo 'Step 3 - This should not execute'",
+							Steps: []config.Step{
+								{Run: "echo 'Step 1 - This will succeed'"},
+								{Run: "exit 1"}, // This will fail
+								{Run: "echo 'Step 3 - This should not execute'"},
 							},
 						},
 					},
@@ -145,10 +145,10 @@ var _ = Describe("User Stories Acceptance Tests", func() {
 						Language: "go",
 						Build: config.Operation{
 							FailFast: false,
-							Steps: []string{
-								"echo 'Step 1 - This will succeed'",
-								"exit 1", // This will fail
-								"echo 'Step 3 - This should still execute'",
+							Steps: []config.Step{
+								{Run: "echo 'Step 1 - This will succeed'"},
+								{Run: "exit 1"}, // This will fail
+								{Run: "echo 'Step 3 - This should still execute'"},
 							},
 						},
 					},
@@ -182,9 +182,9 @@ var _ = Describe("User Stories Acceptance Tests", func() {
 								"CGO_ENABLED": "0",
 								"BUILD_TAG":   "v1.0.0",
 							},
-							Steps: []string{
-								"echo 'Environment variables should be set'",
-								"echo 'go build'",
+							Steps: []config.Step{
+								{Run: "echo 'Environment variables should be set'"},
+								{Run: "echo 'go build'"},
 							},
 						},
 					},
@@ -216,9 +216,9 @@ var _ = Describe("User Stories Acceptance Tests", func() {
 							Env: map[string]string{
 								"CI": "true",
 							},
-							Steps: []string{
-								"go mod download",
-								"go mod verify",
+							Steps: []config.Step{
+								{Run: "go mod download"},
+								{Run: "go mod verify"},
 							},
 						},
 						Build: config.Operation{
@@ -229,10 +229,10 @@ var _ = Describe("User Stories Acceptance Tests", func() {
 								"GOOS":        "linux",
 								"GOARCH":      "amd64",
 							},
-							Steps: []string{
-								"echo 'go test ./...'",
-								"echo 'go build -o app'",
-								"echo 'CI/CD build completed'",
+							Steps: []config.Step{
+								{Run: "echo 'go test ./...'"},
+								{Run: "echo 'go build -o app'"},
+								{Run: "echo 'CI/CD build completed'"},
 							},
 						},
 					},
@@ -42,10 +42,10 @@ var _ = Describe("Build Workflow Integration", func() {
 							Env: map[string]string{
 								"GO_ENV": "test",
 							},
-							Steps: []string{
-								"echo 'Installing dependencies...'",
-								"go mod tidy",
-								"go mod download",
+							Steps: []config.Step{
+								{Run: "echo 'Installing dependencies...'"},
+								{Run: "go mod tidy"},
+								{Run: "go mod download"},
 							},
 						},
 						Build: config.Operation{
@@ -54,10 +54,10 @@ var _ = Describe("Build Workflow Integration", func() {
 								"BUILD_ENV":   "production",
 								"CGO_ENABLED": "0",
 							},
-							Steps: []string{
-								"echo 'Building project...'",
-								"echo 'go build -o testapp'",
-								"echo 'Build completed successfully'",
+							Steps: []config.Step{
+								{Run: "echo 'Building project...'"},
+								{Run: "echo 'go build -o testapp'"},
+								{Run: "echo 'Build completed successfully'"},
 							},
 						},
 					},
@@ -112,15 +112,15 @@ var _ = Describe("Build Workflow Integration", func() {
 					Codebase: config.Codebase{
 						Language: "go",
 						Install: config.Operation{
-							Steps: []string{
-								"echo 'This should be skipped'",
-								"go mod tidy",
+							Steps: []config.Step{
+								{Run: "echo 'This should be skipped'"},
+								{Run: "go mod tidy"},
 							},
 						},
 						Build: config.Operation{
-							Steps: []string{
-								"echo 'Building without install'",
-								"echo 'go build'",
+							Steps: []config.Step{
+								{Run: "echo 'Building without install'"},
+								{Run: "echo 'go build'"},
 							},
 						},
 					},
@@ -160,17 +160,17 @@ var _ = Describe("Build Workflow Integration", func() {
 					Codebase: config.Codebase{
 						Language: "go",
 						Install: config.Operation{
-							Steps: []string{
-								"echo 'Install step 1'",
-								"echo 'Install step 2'",
+							Steps: []config.Step{
+								{Run: "echo 'Install step 1'"},
+								{Run: "echo 'Install step 2'"},
 							},
 						},
 						Build: config.Operation{
 							FailFast: true,
-							Steps: []string{
-								"echo 'Build step 1'",
-								"exit 1",              // This will fail
-								"echo 'Build step 3'", // This should not execute
+							Steps: []config.Step{
+								{Run: "echo 'Build step 1'"},
+								{Run: "exit 1"},              // This will fail
+								{Run: "echo 'Build step 3'"}, // This should not execute
 							},
 						},
 					},
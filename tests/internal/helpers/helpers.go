@@ -93,7 +93,10 @@ type ExecutionRecord struct {
 }
 
 // Exec implements the executor interface for mocking
-func (m *MockExecutor) Exec(ctx context.Context, command string) (executor.Result, error) {
+func (m *MockExecutor) Exec(ctx context.Context, cmd executor.Command) (executor.Result, error) {
+	command := cmd.Run
+	m.env = append(m.env, cmd.Env...)
+
 	// Use custom exec function if provided
 	if m.execFunc != nil {
 		return m.execFunc(ctx, command)
@@ -117,11 +120,6 @@ func (m *MockExecutor) Exec(ctx context.Context, command string) (executor.Resul
 	return result, nil
 }
 
-// AddEnv adds environment variables to the mock executor
-func (m *MockExecutor) AddEnv(env []string) {
-	m.env = append(m.env, env...)
-}
-
 // GetExecutions returns all recorded executions
 func (m *MockExecutor) GetExecutions() []ExecutionRecord {
 	return m.executions
@@ -145,7 +143,7 @@ func (m *MockExecutor) SetExecFunc(fn func(ctx context.Context, command string)
 	m.execFunc = fn
 }
 
-// GetEnv returns the environment variables
+// GetEnv returns the environment variables passed to every execution
 func (m *MockExecutor) GetEnv() []string {
 	return m.env
 }
@@ -36,16 +36,16 @@ var _ = Describe("CLI Commands System Tests", func() {
 					Codebase: config.Codebase{
 						Language: "go",
 						Install: config.Operation{
-							Steps: []string{
-								"echo 'Install step 1'",
-								"echo 'Install step 2'",
+							Steps: []config.Step{
+								{Run: "echo 'Install step 1'"},
+								{Run: "echo 'Install step 2'"},
 							},
 						},
 						Build: config.Operation{
-							Steps: []string{
-								"echo 'Build step 1'",
-								"echo 'Build step 2'",
-								"echo 'Build completed'",
+							Steps: []config.Step{
+								{Run: "echo 'Build step 1'"},
+								{Run: "echo 'Build step 2'"},
+								{Run: "echo 'Build completed'"},
 							},
 						},
 					},
@@ -78,15 +78,15 @@ var _ = Describe("CLI Commands System Tests", func() {
 					Codebase: config.Codebase{
 						Language: "go",
 						Install: config.Operation{
-							Steps: []string{
-								"echo 'This should be skipped'",
-								"exit 1", // This would fail if executed
+							Steps: []config.Step{
+								{Run: "echo 'This should be skipped'"},
+								{Run: "exit 1"}, // This would fail if executed
 							},
 						},
 						Build: config.Operation{
-							Steps: []string{
-								"echo 'Build step executed'",
-								"echo 'Build completed'",
+							Steps: []config.Step{
+								{Run: "echo 'Build step executed'"},
+								{Run: "echo 'Build completed'"},
 							},
 						},
 					},
@@ -113,10 +113,10 @@ var _ = Describe("CLI Commands System Tests", func() {
 						Language: "go",
 						Build: config.Operation{
 							FailFast: true,
-							Steps: []string{
-								"echo 'Step 1'",
-								"exit 1",        // This will fail
-								"echo 'Step 3'", // This should not execute
+							Steps: []config.Step{
+								{Run: "echo 'Step 1'"},
+								{Run: "exit 1"},        // This will fail
+								{Run: "echo 'Step 3'"}, // This should not execute
 							},
 						},
 					},
@@ -135,6 +135,65 @@ var _ = Describe("CLI Commands System Tests", func() {
 		})
 	})
 
+	Describe("Environment Handling", func() {
+		Context("when an operation declares environment variables", func() {
+			It("should make them available to every step", func() {
+				// Given: A build operation with declared environment variables
+				projectConfig := &config.ProjectDefinition{
+					Name:    "EnvProject",
+					Version: "1.0.0",
+					Codebase: config.Codebase{
+						Build: config.Operation{
+							FailFast: true,
+							Env: map[string]string{
+								"BUILD_ENV": "production",
+							},
+							Steps: []config.Step{
+								{Run: `test "$BUILD_ENV" = production`},
+								{Run: `test "$STEP_ENV" = set`, Env: map[string]string{"STEP_ENV": "set"}},
+							},
+						},
+					},
+				}
+
+				// When: The build is executed
+				err := config.Build(ctx, realExecutor, projectConfig, &config.BuildOptions{NoInstall: true})
+
+				// Then: The steps should see the declared values
+				Expect(err).To(BeNil())
+			})
+		})
+
+		Context("when an operation uses the clean environment mode", func() {
+			It("should not leak undeclared variables into the steps", func() {
+				// Given: A variable in the calling environment
+				GinkgoT().Setenv("OPSRUNNER_LEAKED_SECRET", "hunter2")
+
+				// And: A build operation using the clean environment mode
+				projectConfig := &config.ProjectDefinition{
+					Name:    "CleanEnvProject",
+					Version: "1.0.0",
+					Codebase: config.Codebase{
+						Build: config.Operation{
+							FailFast: true,
+							EnvMode:  config.EnvModeClean,
+							Steps: []config.Step{
+								{Run: `test -z "$OPSRUNNER_LEAKED_SECRET"`},
+								{Run: `test -n "$PATH"`},
+							},
+						},
+					},
+				}
+
+				// When: The build is executed
+				err := config.Build(ctx, realExecutor, projectConfig, &config.BuildOptions{NoInstall: true})
+
+				// Then: The steps should only see the baseline variables
+				Expect(err).To(BeNil())
+			})
+		})
+	})
+
 	Describe("Command Registry", func() {
 		Context("when creating a command registry", func() {
 			It("should register commands correctly", func() {